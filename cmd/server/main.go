@@ -3,12 +3,12 @@ package main
 import (
     "log"
     "net/http"
-    "time"
-    "math/rand"
 
+    battlehandler "github.com/matheustorresii/tyrants-back/internal/battle"
     "github.com/matheustorresii/tyrants-back/internal/db"
     newshandler "github.com/matheustorresii/tyrants-back/internal/news"
     "github.com/matheustorresii/tyrants-back/internal/scene"
+    typecharthandler "github.com/matheustorresii/tyrants-back/internal/typechart"
     tyranthandler "github.com/matheustorresii/tyrants-back/internal/tyrant"
     userhandler "github.com/matheustorresii/tyrants-back/internal/user"
 )
@@ -21,24 +21,31 @@ func main() {
     }
 
     // Wire HTTP handlers
-    h := userhandler.NewHandler(storage)
+    rooms := scene.NewRegistry(storage, scene.NewMathRand)
+    h := userhandler.NewHandler(storage, rooms)
     nh := newshandler.NewHandler(storage)
     th := tyranthandler.NewHandler(storage)
-    hub := scene.NewHub(storage)
+    tch := typecharthandler.NewHandler(storage)
+    bh := battlehandler.NewHandler(storage)
 
     mux := http.NewServeMux()
     mux.HandleFunc("/users", h.PostUsers)
     mux.HandleFunc("/login", h.PostLogin)
-    mux.HandleFunc("/users/", h.PutUser)
+    mux.HandleFunc("/users/", h.UsersItem)
     mux.HandleFunc("/news", nh.NewsCollection)
     mux.HandleFunc("/news/", nh.NewsItem)
     mux.HandleFunc("/tyrants", th.TyrantsCollection)
     mux.HandleFunc("/tyrants/", th.TyrantsItem)
-    mux.HandleFunc("/scene/ws", hub.ServeWS)
+    mux.HandleFunc("/types/chart", tch.ChartCollection)
+    mux.HandleFunc("/types/chart/", tch.ChartItem)
+    mux.HandleFunc("/battles", bh.BattlesCollection)
+    mux.HandleFunc("/battles/", bh.BattlesItem)
+    mux.HandleFunc("/scene/ws", rooms.ServeWS)
+    mux.HandleFunc("/scene/rooms", rooms.Rooms)
+    mux.HandleFunc("/scene/", rooms.ServeRoomWS)
 
     addr := ":8080"
     log.Printf("Tyrants server listening on http://localhost:8080 (all interfaces)")
-    randSeedOnce()
     if err := http.ListenAndServe(addr, loggingMiddleware(corsMiddleware(mux))); err != nil {
         log.Fatalf("server error: %v", err)
     }
@@ -52,16 +59,11 @@ func loggingMiddleware(next http.Handler) http.Handler {
     })
 }
 
-func randSeedOnce() {
-    // Seed default rand used in scene damage calculations
-    rand.Seed(time.Now().UnixNano())
-}
-
 func corsMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Access-Control-Allow-Origin", "*")
         w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-User-ID")
         if r.Method == http.MethodOptions {
             w.WriteHeader(http.StatusNoContent)
             return
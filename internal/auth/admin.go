@@ -0,0 +1,54 @@
+package auth
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/matheustorresii/tyrants-back/internal/db"
+    "github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// HeaderUserID carries the id of the calling user (same id used in /login).
+const HeaderUserID = "X-User-ID"
+
+// QueryUserID is the query parameter alternative to HeaderUserID, for clients
+// that cannot set headers (e.g. browser WebSockets).
+const QueryUserID = "user"
+
+// CallerID returns the id of the calling user from HeaderUserID, falling back
+// to QueryUserID. It is empty for anonymous callers.
+func CallerID(r *http.Request) string {
+    if id := r.Header.Get(HeaderUserID); id != "" {
+        return id
+    }
+    return r.URL.Query().Get(QueryUserID)
+}
+
+// UserLookup is the persistence dependency needed to resolve callers.
+type UserLookup interface {
+    GetUser(id string) (models.User, error)
+}
+
+// RequireAdmin checks that the caller identified by HeaderUserID is an admin.
+// On failure it writes 401 (unknown caller) or 403 (not admin) and returns false.
+func RequireAdmin(w http.ResponseWriter, r *http.Request, users UserLookup) bool {
+    id := r.Header.Get(HeaderUserID)
+    if id == "" {
+        http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+        return false
+    }
+    u, err := users.GetUser(id)
+    if err != nil {
+        if errors.Is(err, db.ErrUserNotFound) {
+            http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+            return false
+        }
+        http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        return false
+    }
+    if !u.Admin {
+        http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+        return false
+    }
+    return true
+}
@@ -0,0 +1,153 @@
+package battle
+
+import (
+    "encoding/json"
+    "errors"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "github.com/gorilla/websocket"
+    "github.com/matheustorresii/tyrants-back/internal/db"
+    "github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Service defines the behaviors the handler requires from the persistence layer.
+type Service interface {
+    ListBattles() ([]models.Battle, error)
+    GetBattle(id string) (models.Battle, error)
+}
+
+// Handler provides HTTP handlers for battle history and replays.
+type Handler struct {
+    svc Service
+}
+
+// NewHandler creates a new battle Handler.
+func NewHandler(svc Service) *Handler {
+    return &Handler{svc: svc}
+}
+
+// maxReplayGap caps the real-time pause between two replayed events, so long
+// breaks at the table do not stall the replay.
+const maxReplayGap = 5 * time.Second
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+// replayControl is the message a replay client may send to change speed mid-stream.
+type replayControl struct {
+    Speed float64 `json:"speed"`
+}
+
+// BattlesCollection handles GET /battles
+func (h *Handler) BattlesCollection(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
+    items, err := h.svc.ListBattles()
+    if err != nil {
+        http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(items)
+}
+
+// BattlesItem handles GET /battles/{id} and the replay socket at /battles/{id}/replay
+func (h *Handler) BattlesItem(w http.ResponseWriter, r *http.Request) {
+    if !strings.HasPrefix(r.URL.Path, "/battles/") {
+        http.NotFound(w, r)
+        return
+    }
+    rest := strings.TrimPrefix(r.URL.Path, "/battles/")
+    id, suffix, _ := strings.Cut(rest, "/")
+    if id == "" || (suffix != "" && suffix != "replay") {
+        http.NotFound(w, r)
+        return
+    }
+    if r.Method != http.MethodGet {
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
+
+    item, err := h.svc.GetBattle(id)
+    if err != nil {
+        if errors.Is(err, db.ErrBattleNotFound) {
+            http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+            return
+        }
+        http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        return
+    }
+    if suffix == "replay" {
+        h.replay(w, r, item)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(item)
+}
+
+// replay re-streams a battle's events over a WebSocket, keeping the original
+// spacing between events divided by the speed factor (?speed=2 plays twice as fast).
+// The client can change the speed at any time by sending {"speed": n}.
+func (h *Handler) replay(w http.ResponseWriter, r *http.Request, b models.Battle) {
+    speed := 1.0
+    if v := r.URL.Query().Get("speed"); v != "" {
+        f, err := strconv.ParseFloat(v, 64)
+        if err != nil || f <= 0 || math.IsInf(f, 0) {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        speed = f
+    }
+    conn, err := upgrader.Upgrade(w, r, nil)
+    if err != nil {
+        http.Error(w, "upgrade failed", http.StatusBadRequest)
+        return
+    }
+    defer conn.Close()
+
+    var speedBits atomic.Uint64
+    speedBits.Store(math.Float64bits(speed))
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for {
+            var ctl replayControl
+            if err := conn.ReadJSON(&ctl); err != nil {
+                return
+            }
+            if ctl.Speed > 0 && !math.IsInf(ctl.Speed, 0) {
+                speedBits.Store(math.Float64bits(ctl.Speed))
+            }
+        }
+    }()
+
+    var prev time.Time
+    for i, e := range b.Events {
+        at, err := time.Parse(time.RFC3339Nano, e.CreatedAt)
+        if err == nil && i > 0 && !prev.IsZero() {
+            gap := at.Sub(prev)
+            if gap > maxReplayGap {
+                gap = maxReplayGap
+            }
+            wait := time.Duration(float64(gap) / math.Float64frombits(speedBits.Load()))
+            select {
+            case <-time.After(wait):
+            case <-done:
+                return
+            }
+        }
+        if err == nil {
+            prev = at
+        }
+        if err := conn.WriteJSON(map[string]any{"replay": b.ID, "event": e}); err != nil {
+            return
+        }
+    }
+    _ = conn.WriteJSON(map[string]any{"replayEnd": b.ID, "outcome": b.Outcome})
+}
@@ -7,11 +7,23 @@ var (
     ErrUserExists   = errors.New("user already exists")
     ErrUserNotFound = errors.New("user not found")
 
+    ErrUserItemNotFound = errors.New("user item not found")
+
     ErrNewsExists   = errors.New("news already exists")
     ErrNewsNotFound = errors.New("news not found")
 
     ErrTyrantExists   = errors.New("tyrant already exists")
     ErrTyrantNotFound = errors.New("tyrant not found")
+
+    ErrEvolutionNotFound           = errors.New("evolution not found")
+    ErrEvolutionRequirementsNotMet = errors.New("evolution requirements not met")
+
+    ErrTypeEffectivenessNotFound = errors.New("type effectiveness not found")
+
+    ErrSceneStateNotFound = errors.New("scene state not found")
+
+    ErrBattleExists   = errors.New("battle already exists")
+    ErrBattleNotFound = errors.New("battle not found")
 )
 
 
@@ -6,6 +6,7 @@ import (
     "errors"
     "fmt"
     "strings"
+    "time"
 
     _ "modernc.org/sqlite"
 
@@ -52,6 +53,8 @@ func (s *SQLiteDB) migrate(ctx context.Context) error {
             PRIMARY KEY (user_id, name),
             FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
         );`,
+        // add quantity column if missing (ignore error if already exists)
+        `ALTER TABLE user_items ADD COLUMN quantity INTEGER NOT NULL DEFAULT 1;`,
         `CREATE TABLE IF NOT EXISTS news (
             id TEXT PRIMARY KEY,
             image TEXT NOT NULL,
@@ -78,6 +81,10 @@ func (s *SQLiteDB) migrate(ctx context.Context) error {
             PRIMARY KEY (tyrant_id, evolution_id),
             FOREIGN KEY (tyrant_id) REFERENCES tyrants(id) ON DELETE CASCADE
         );`,
+        // evolution requirements per edge (ignore errors if columns already exist)
+        `ALTER TABLE tyrant_evolutions ADD COLUMN min_level INTEGER NOT NULL DEFAULT 0;`,
+        `ALTER TABLE tyrant_evolutions ADD COLUMN min_xp INTEGER NOT NULL DEFAULT 0;`,
+        `ALTER TABLE tyrant_evolutions ADD COLUMN item TEXT NULL;`,
         `CREATE TABLE IF NOT EXISTS tyrant_attacks (
             tyrant_id TEXT NOT NULL,
             name TEXT NOT NULL,
@@ -86,6 +93,7 @@ func (s *SQLiteDB) migrate(ctx context.Context) error {
             PRIMARY KEY (tyrant_id, name),
             FOREIGN KEY (tyrant_id) REFERENCES tyrants(id) ON DELETE CASCADE
         );`,
+        `ALTER TABLE tyrant_attacks ADD COLUMN accuracy INTEGER NOT NULL DEFAULT 100;`,
         `CREATE TABLE IF NOT EXISTS tyrant_attack_attributes (
             tyrant_id TEXT NOT NULL,
             attack_name TEXT NOT NULL,
@@ -93,6 +101,46 @@ func (s *SQLiteDB) migrate(ctx context.Context) error {
             PRIMARY KEY (tyrant_id, attack_name, attribute),
             FOREIGN KEY (tyrant_id, attack_name) REFERENCES tyrant_attacks(tyrant_id, name) ON DELETE CASCADE
         );`,
+        `CREATE TABLE IF NOT EXISTS tyrant_types (
+            tyrant_id TEXT NOT NULL,
+            type TEXT NOT NULL,
+            PRIMARY KEY (tyrant_id, type),
+            FOREIGN KEY (tyrant_id) REFERENCES tyrants(id) ON DELETE CASCADE
+        );`,
+        // Scene battle checkpoints: one JSON snapshot per room
+        `CREATE TABLE IF NOT EXISTS scene_states (
+            room_id TEXT PRIMARY KEY,
+            state TEXT NOT NULL,
+            updated_at TEXT NOT NULL
+        );`,
+        // the scene image is kept apart so checkpoints do not rewrite it
+        `ALTER TABLE scene_states ADD COLUMN image TEXT NULL;`,
+        // Battle log: one row per battle plus its append-only events
+        `CREATE TABLE IF NOT EXISTS battles (
+            id TEXT PRIMARY KEY,
+            room_id TEXT NOT NULL,
+            started_at TEXT NOT NULL,
+            ended_at TEXT NULL,
+            outcome TEXT NULL
+        );`,
+        `CREATE INDEX IF NOT EXISTS idx_battles_started_at ON battles(started_at);`,
+        `ALTER TABLE battles ADD COLUMN seed INTEGER NOT NULL DEFAULT 0;`,
+        `CREATE TABLE IF NOT EXISTS battle_events (
+            battle_id TEXT NOT NULL,
+            seq INTEGER NOT NULL,
+            type TEXT NOT NULL,
+            data TEXT NOT NULL,
+            created_at TEXT NOT NULL,
+            PRIMARY KEY (battle_id, seq),
+            FOREIGN KEY (battle_id) REFERENCES battles(id) ON DELETE CASCADE
+        );`,
+        // Elemental chart: attack attribute vs defender type -> damage multiplier
+        `CREATE TABLE IF NOT EXISTS type_effectiveness (
+            attribute TEXT NOT NULL,
+            defender_type TEXT NOT NULL,
+            multiplier REAL NOT NULL,
+            PRIMARY KEY (attribute, defender_type)
+        );`,
     }
     for _, stmt := range stmts {
         if _, err := s.db.ExecContext(ctx, stmt); err != nil {
@@ -165,7 +213,7 @@ func (s *SQLiteDB) GetUserDetails(id string) (models.UserDetails, error) {
         }
     }
     if !out.Admin {
-        itemsRows, err := s.db.Query(`SELECT name, asset FROM user_items WHERE user_id = ? ORDER BY name ASC`, id)
+        itemsRows, err := s.db.Query(`SELECT name, asset, quantity FROM user_items WHERE user_id = ? ORDER BY name ASC`, id)
         if err != nil {
             return models.UserDetails{}, err
         }
@@ -173,7 +221,7 @@ func (s *SQLiteDB) GetUserDetails(id string) (models.UserDetails, error) {
         var list []models.UserItem
         for itemsRows.Next() {
             var it models.UserItem
-            if err := itemsRows.Scan(&it.Name, &it.Asset); err != nil {
+            if err := itemsRows.Scan(&it.Name, &it.Asset, &it.Quantity); err != nil {
                 return models.UserDetails{}, err
             }
             list = append(list, it)
@@ -208,7 +256,12 @@ func (s *SQLiteDB) UpdateUser(id string, upd models.UserUpdate) (models.UserDeta
     }
 
     if upd.TyrantID != nil {
-        if _, err := tx.Exec(`UPDATE users SET tyrant_id = ? WHERE id = ?`, upd.TyrantID, id); err != nil {
+        // an empty id releases the user's Tyrant
+        var tyrantID any = *upd.TyrantID
+        if *upd.TyrantID == "" {
+            tyrantID = nil
+        }
+        if _, err := tx.Exec(`UPDATE users SET tyrant_id = ? WHERE id = ?`, tyrantID, id); err != nil {
             return models.UserDetails{}, err
         }
     }
@@ -222,7 +275,11 @@ func (s *SQLiteDB) UpdateUser(id string, upd models.UserUpdate) (models.UserDeta
             return models.UserDetails{}, err
         }
         for _, it := range *upd.Items {
-            if _, err := tx.Exec(`INSERT INTO user_items(user_id, name, asset) VALUES(?, ?, ?)`, id, it.Name, it.Asset); err != nil {
+            qty := it.Quantity
+            if qty <= 0 {
+                qty = 1
+            }
+            if _, err := tx.Exec(`INSERT INTO user_items(user_id, name, asset, quantity) VALUES(?, ?, ?, ?)`, id, it.Name, it.Asset, qty); err != nil {
                 return models.UserDetails{}, err
             }
         }
@@ -233,6 +290,52 @@ func (s *SQLiteDB) UpdateUser(id string, upd models.UserUpdate) (models.UserDeta
     return s.GetUserDetails(id)
 }
 
+// AwardXP adds XP to several users in one transaction. Unknown users are skipped.
+func (s *SQLiteDB) AwardXP(awards map[string]int) error {
+    tx, err := s.db.Begin()
+    if err != nil {
+        return err
+    }
+    defer func() { _ = tx.Rollback() }()
+
+    for id, xp := range awards {
+        if _, err := tx.Exec(`UPDATE users SET xp = xp + ? WHERE id = ?`, xp, id); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// ConsumeUserItem uses one unit of an item, removing it when the last unit is
+// gone, and returns how many are left.
+func (s *SQLiteDB) ConsumeUserItem(userID, name string) (int, error) {
+    tx, err := s.db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer func() { _ = tx.Rollback() }()
+
+    var qty int
+    if err := tx.QueryRow(`SELECT quantity FROM user_items WHERE user_id = ? AND name = ?`, userID, name).Scan(&qty); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return 0, ErrUserItemNotFound
+        }
+        return 0, err
+    }
+    if qty <= 1 {
+        _, err = tx.Exec(`DELETE FROM user_items WHERE user_id = ? AND name = ?`, userID, name)
+    } else {
+        _, err = tx.Exec(`UPDATE user_items SET quantity = quantity - 1 WHERE user_id = ? AND name = ?`, userID, name)
+    }
+    if err != nil {
+        return 0, err
+    }
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return qty - 1, nil
+}
+
 // News
 
 func (s *SQLiteDB) CreateNews(n models.News) error {
@@ -336,13 +439,18 @@ func (s *SQLiteDB) CreateTyrant(t models.Tyrant) error {
         }
         return err
     }
+    for _, typ := range t.Types {
+        if _, err := tx.Exec(`INSERT INTO tyrant_types(tyrant_id, type) VALUES(?, ?)`, t.ID, typ); err != nil {
+            return err
+        }
+    }
     for _, evo := range t.Evolutions {
         if _, err := tx.Exec(`INSERT INTO tyrant_evolutions(tyrant_id, evolution_id) VALUES(?, ?)`, t.ID, evo); err != nil {
             return err
         }
     }
     for _, a := range t.Attacks {
-        if _, err := tx.Exec(`INSERT INTO tyrant_attacks(tyrant_id, name, power, pp) VALUES(?, ?, ?, ?)`, t.ID, a.Name, a.Power, a.PP); err != nil {
+        if _, err := tx.Exec(`INSERT INTO tyrant_attacks(tyrant_id, name, power, pp, accuracy) VALUES(?, ?, ?, ?, ?)`, t.ID, a.Name, a.Power, a.PP, a.Accuracy); err != nil {
             return err
         }
         for _, attr := range a.Attributes {
@@ -370,6 +478,19 @@ func (s *SQLiteDB) GetTyrant(id string) (models.Tyrant, error) {
     if nickname.Valid {
         t.Nickname = &nickname.String
     }
+    // Types
+    typeRows, err := s.db.Query(`SELECT type FROM tyrant_types WHERE tyrant_id = ? ORDER BY type ASC`, id)
+    if err != nil {
+        return models.Tyrant{}, err
+    }
+    defer typeRows.Close()
+    for typeRows.Next() {
+        var typ string
+        if err := typeRows.Scan(&typ); err != nil {
+            return models.Tyrant{}, err
+        }
+        t.Types = append(t.Types, typ)
+    }
     // Evolutions
     evoRows, err := s.db.Query(`SELECT evolution_id FROM tyrant_evolutions WHERE tyrant_id = ? ORDER BY evolution_id ASC`, id)
     if err != nil {
@@ -384,14 +505,14 @@ func (s *SQLiteDB) GetTyrant(id string) (models.Tyrant, error) {
         t.Evolutions = append(t.Evolutions, evo)
     }
     // Attacks
-    atkRows, err := s.db.Query(`SELECT name, power, pp FROM tyrant_attacks WHERE tyrant_id = ? ORDER BY name ASC`, id)
+    atkRows, err := s.db.Query(`SELECT name, power, pp, accuracy FROM tyrant_attacks WHERE tyrant_id = ? ORDER BY name ASC`, id)
     if err != nil {
         return models.Tyrant{}, err
     }
     defer atkRows.Close()
     for atkRows.Next() {
         var a models.Attack
-        if err := atkRows.Scan(&a.Name, &a.Power, &a.PP); err != nil {
+        if err := atkRows.Scan(&a.Name, &a.Power, &a.PP, &a.Accuracy); err != nil {
             return models.Tyrant{}, err
         }
         // attributes per attack
@@ -451,13 +572,45 @@ func (s *SQLiteDB) UpdateTyrant(id string, t models.Tyrant) (models.Tyrant, erro
     if affected == 0 {
         return models.Tyrant{}, ErrTyrantNotFound
     }
-    // Replace evolutions only if provided (nil means keep existing)
-    if t.Evolutions != nil {
-        if _, err := tx.Exec(`DELETE FROM tyrant_evolutions WHERE tyrant_id = ?`, id); err != nil {
+    // Replace types only if provided (nil means keep existing)
+    if t.Types != nil {
+        if _, err := tx.Exec(`DELETE FROM tyrant_types WHERE tyrant_id = ?`, id); err != nil {
             return models.Tyrant{}, err
         }
+        for _, typ := range t.Types {
+            if _, err := tx.Exec(`INSERT INTO tyrant_types(tyrant_id, type) VALUES(?, ?)`, id, typ); err != nil {
+                return models.Tyrant{}, err
+            }
+        }
+    }
+    // Replace evolutions only if provided (nil means keep existing); edges that
+    // stay keep their requirements
+    if t.Evolutions != nil {
+        keep := make(map[string]bool, len(t.Evolutions))
         for _, evo := range t.Evolutions {
-            if _, err := tx.Exec(`INSERT INTO tyrant_evolutions(tyrant_id, evolution_id) VALUES(?, ?)`, id, evo); err != nil {
+            keep[evo] = true
+            if _, err := tx.Exec(`INSERT INTO tyrant_evolutions(tyrant_id, evolution_id) VALUES(?, ?) ON CONFLICT(tyrant_id, evolution_id) DO NOTHING`, id, evo); err != nil {
+                return models.Tyrant{}, err
+            }
+        }
+        rows, err := tx.Query(`SELECT evolution_id FROM tyrant_evolutions WHERE tyrant_id = ?`, id)
+        if err != nil {
+            return models.Tyrant{}, err
+        }
+        var stale []string
+        for rows.Next() {
+            var evo string
+            if err := rows.Scan(&evo); err != nil {
+                rows.Close()
+                return models.Tyrant{}, err
+            }
+            if !keep[evo] {
+                stale = append(stale, evo)
+            }
+        }
+        rows.Close()
+        for _, evo := range stale {
+            if _, err := tx.Exec(`DELETE FROM tyrant_evolutions WHERE tyrant_id = ? AND evolution_id = ?`, id, evo); err != nil {
                 return models.Tyrant{}, err
             }
         }
@@ -471,7 +624,7 @@ func (s *SQLiteDB) UpdateTyrant(id string, t models.Tyrant) (models.Tyrant, erro
             return models.Tyrant{}, err
         }
         for _, a := range t.Attacks {
-            if _, err := tx.Exec(`INSERT INTO tyrant_attacks(tyrant_id, name, power, pp) VALUES(?, ?, ?, ?)`, id, a.Name, a.Power, a.PP); err != nil {
+            if _, err := tx.Exec(`INSERT INTO tyrant_attacks(tyrant_id, name, power, pp, accuracy) VALUES(?, ?, ?, ?, ?)`, id, a.Name, a.Power, a.PP, a.Accuracy); err != nil {
                 return models.Tyrant{}, err
             }
             for _, attr := range a.Attributes {
@@ -498,6 +651,383 @@ func (s *SQLiteDB) DeleteTyrant(id string) error {
     }
     return nil
 }
+
+// Evolutions
+
+// ListEvolutionRules returns the requirements of every evolution of a tyrant.
+func (s *SQLiteDB) ListEvolutionRules(from string) ([]models.EvolutionRule, error) {
+    if _, err := s.GetTyrant(from); err != nil {
+        return nil, err
+    }
+    rows, err := s.db.Query(`SELECT tyrant_id, evolution_id, min_level, min_xp, item FROM tyrant_evolutions WHERE tyrant_id = ? ORDER BY evolution_id ASC`, from)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    out := make([]models.EvolutionRule, 0)
+    for rows.Next() {
+        r, err := scanEvolutionRule(rows)
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, r)
+    }
+    return out, rows.Err()
+}
+
+// GetEvolutionRule returns the requirements of a single evolution edge.
+func (s *SQLiteDB) GetEvolutionRule(from, to string) (models.EvolutionRule, error) {
+    return getEvolutionRule(s.db.QueryRow, from, to)
+}
+
+// SetEvolutionRule creates the edge if needed and replaces its requirements.
+// Both tyrants must exist.
+func (s *SQLiteDB) SetEvolutionRule(r models.EvolutionRule) error {
+    for _, id := range []string{r.From, r.To} {
+        if _, err := s.GetTyrant(id); err != nil {
+            return err
+        }
+    }
+    _, err := s.db.Exec(`INSERT INTO tyrant_evolutions(tyrant_id, evolution_id, min_level, min_xp, item) VALUES(?, ?, ?, ?, ?)
+        ON CONFLICT(tyrant_id, evolution_id) DO UPDATE SET min_level = excluded.min_level, min_xp = excluded.min_xp, item = excluded.item`,
+        r.From, r.To, r.MinLevel, r.MinXP, r.Item,
+    )
+    return err
+}
+
+// DeleteEvolutionRule removes an evolution edge.
+func (s *SQLiteDB) DeleteEvolutionRule(from, to string) error {
+    res, err := s.db.Exec(`DELETE FROM tyrant_evolutions WHERE tyrant_id = ? AND evolution_id = ?`, from, to)
+    if err != nil {
+        return err
+    }
+    affected, _ := res.RowsAffected()
+    if affected == 0 {
+        return ErrEvolutionNotFound
+    }
+    return nil
+}
+
+// EvolveUser swaps the user's tyrant for one of its evolutions, checking the
+// edge requirements and consuming the required item in the same transaction.
+// It returns the rule that was applied.
+func (s *SQLiteDB) EvolveUser(userID, to string) (models.EvolutionRule, error) {
+    tx, err := s.db.Begin()
+    if err != nil {
+        return models.EvolutionRule{}, err
+    }
+    defer func() { _ = tx.Rollback() }()
+
+    var from sql.NullString
+    var xp int
+    if err := tx.QueryRow(`SELECT tyrant_id, xp FROM users WHERE id = ?`, userID).Scan(&from, &xp); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return models.EvolutionRule{}, ErrUserNotFound
+        }
+        return models.EvolutionRule{}, err
+    }
+    if !from.Valid || from.String == "" {
+        return models.EvolutionRule{}, ErrEvolutionNotFound
+    }
+    r, err := getEvolutionRule(tx.QueryRow, from.String, to)
+    if err != nil {
+        return models.EvolutionRule{}, err
+    }
+    var exists int
+    if err := tx.QueryRow(`SELECT 1 FROM tyrants WHERE id = ?`, to).Scan(&exists); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return models.EvolutionRule{}, ErrTyrantNotFound
+        }
+        return models.EvolutionRule{}, err
+    }
+    if xp < r.MinXP || models.LevelForXP(xp) < r.MinLevel {
+        return models.EvolutionRule{}, ErrEvolutionRequirementsNotMet
+    }
+    if r.Item != nil {
+        var qty int
+        if err := tx.QueryRow(`SELECT quantity FROM user_items WHERE user_id = ? AND name = ?`, userID, *r.Item).Scan(&qty); err != nil {
+            if errors.Is(err, sql.ErrNoRows) {
+                return models.EvolutionRule{}, ErrEvolutionRequirementsNotMet
+            }
+            return models.EvolutionRule{}, err
+        }
+        if qty <= 1 {
+            _, err = tx.Exec(`DELETE FROM user_items WHERE user_id = ? AND name = ?`, userID, *r.Item)
+        } else {
+            _, err = tx.Exec(`UPDATE user_items SET quantity = quantity - 1 WHERE user_id = ? AND name = ?`, userID, *r.Item)
+        }
+        if err != nil {
+            return models.EvolutionRule{}, err
+        }
+    }
+    if _, err := tx.Exec(`UPDATE users SET tyrant_id = ? WHERE id = ?`, to, userID); err != nil {
+        return models.EvolutionRule{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return models.EvolutionRule{}, err
+    }
+    return r, nil
+}
+
+func getEvolutionRule(queryRow func(query string, args ...any) *sql.Row, from, to string) (models.EvolutionRule, error) {
+    row := queryRow(`SELECT tyrant_id, evolution_id, min_level, min_xp, item FROM tyrant_evolutions WHERE tyrant_id = ? AND evolution_id = ?`, from, to)
+    r, err := scanEvolutionRule(row)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return models.EvolutionRule{}, ErrEvolutionNotFound
+        }
+        return models.EvolutionRule{}, err
+    }
+    return r, nil
+}
+
+func scanEvolutionRule(row rowScanner) (models.EvolutionRule, error) {
+    var r models.EvolutionRule
+    var item sql.NullString
+    if err := row.Scan(&r.From, &r.To, &r.MinLevel, &r.MinXP, &item); err != nil {
+        return models.EvolutionRule{}, err
+    }
+    if item.Valid {
+        r.Item = &item.String
+    }
+    return r, nil
+}
+
+// Type chart
+
+func (s *SQLiteDB) ListTypeChart() ([]models.TypeEffectiveness, error) {
+    rows, err := s.db.Query(`SELECT attribute, defender_type, multiplier FROM type_effectiveness ORDER BY attribute ASC, defender_type ASC`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    list := make([]models.TypeEffectiveness, 0)
+    for rows.Next() {
+        var e models.TypeEffectiveness
+        if err := rows.Scan(&e.Attribute, &e.DefenderType, &e.Multiplier); err != nil {
+            return nil, err
+        }
+        list = append(list, e)
+    }
+    return list, rows.Err()
+}
+
+// ReplaceTypeChart swaps the whole chart for the given entries.
+func (s *SQLiteDB) ReplaceTypeChart(entries []models.TypeEffectiveness) error {
+    tx, err := s.db.Begin()
+    if err != nil {
+        return err
+    }
+    defer func() { _ = tx.Rollback() }()
+
+    if _, err := tx.Exec(`DELETE FROM type_effectiveness`); err != nil {
+        return err
+    }
+    for _, e := range entries {
+        if _, err := tx.Exec(`INSERT INTO type_effectiveness(attribute, defender_type, multiplier) VALUES(?, ?, ?)`,
+            e.Attribute, e.DefenderType, e.Multiplier,
+        ); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// SetTypeEffectiveness creates or replaces a single chart cell.
+func (s *SQLiteDB) SetTypeEffectiveness(e models.TypeEffectiveness) error {
+    _, err := s.db.Exec(`INSERT INTO type_effectiveness(attribute, defender_type, multiplier) VALUES(?, ?, ?)
+        ON CONFLICT(attribute, defender_type) DO UPDATE SET multiplier = excluded.multiplier`,
+        e.Attribute, e.DefenderType, e.Multiplier,
+    )
+    return err
+}
+
+func (s *SQLiteDB) DeleteTypeEffectiveness(attribute, defenderType string) error {
+    res, err := s.db.Exec(`DELETE FROM type_effectiveness WHERE attribute = ? AND defender_type = ?`, attribute, defenderType)
+    if err != nil {
+        return err
+    }
+    affected, _ := res.RowsAffected()
+    if affected == 0 {
+        return ErrTypeEffectivenessNotFound
+    }
+    return nil
+}
+
+// Scene state
+
+// SaveSceneState stores the latest checkpoint for a scene room.
+func (s *SQLiteDB) SaveSceneState(roomID string, state []byte) error {
+    _, err := s.db.Exec(`INSERT INTO scene_states(room_id, state, updated_at) VALUES(?, ?, ?)
+        ON CONFLICT(room_id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at`,
+        roomID, string(state), time.Now().UTC().Format(time.RFC3339),
+    )
+    return err
+}
+
+func (s *SQLiteDB) LoadSceneState(roomID string) ([]byte, error) {
+    var state string
+    if err := s.db.QueryRow(`SELECT state FROM scene_states WHERE room_id = ?`, roomID).Scan(&state); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrSceneStateNotFound
+        }
+        return nil, err
+    }
+    return []byte(state), nil
+}
+
+// ListSceneStates returns every stored checkpoint keyed by room id.
+func (s *SQLiteDB) ListSceneStates() (map[string][]byte, error) {
+    rows, err := s.db.Query(`SELECT room_id, state FROM scene_states ORDER BY room_id ASC`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    out := make(map[string][]byte)
+    for rows.Next() {
+        var id, state string
+        if err := rows.Scan(&id, &state); err != nil {
+            return nil, err
+        }
+        out[id] = []byte(state)
+    }
+    return out, rows.Err()
+}
+
+// SaveSceneImage stores the image shown in a scene room next to its
+// checkpoint; a nil image clears it.
+func (s *SQLiteDB) SaveSceneImage(roomID string, image []byte) error {
+    var v any
+    if image != nil {
+        v = string(image)
+    }
+    _, err := s.db.Exec(`INSERT INTO scene_states(room_id, state, image, updated_at) VALUES(?, '{}', ?, ?)
+        ON CONFLICT(room_id) DO UPDATE SET image = excluded.image, updated_at = excluded.updated_at`,
+        roomID, v, time.Now().UTC().Format(time.RFC3339),
+    )
+    return err
+}
+
+// LoadSceneImage returns the stored image of a scene room, or nil if it has none.
+func (s *SQLiteDB) LoadSceneImage(roomID string) ([]byte, error) {
+    var image sql.NullString
+    if err := s.db.QueryRow(`SELECT image FROM scene_states WHERE room_id = ?`, roomID).Scan(&image); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrSceneStateNotFound
+        }
+        return nil, err
+    }
+    if !image.Valid {
+        return nil, nil
+    }
+    return []byte(image.String), nil
+}
+
+func (s *SQLiteDB) DeleteSceneState(roomID string) error {
+    _, err := s.db.Exec(`DELETE FROM scene_states WHERE room_id = ?`, roomID)
+    return err
+}
+
+// Battles
+
+func (s *SQLiteDB) CreateBattle(b models.Battle) error {
+    if b.ID == "" {
+        return errors.New("battle id cannot be empty")
+    }
+    _, err := s.db.Exec(`INSERT INTO battles(id, room_id, started_at, seed) VALUES(?, ?, ?, ?)`, b.ID, b.RoomID, b.StartedAt, b.Seed)
+    if err != nil {
+        if isUniqueConstraintError(err) {
+            return ErrBattleExists
+        }
+        return err
+    }
+    return nil
+}
+
+// AppendBattleEvent adds an event to a battle log.
+func (s *SQLiteDB) AppendBattleEvent(battleID string, e models.BattleEvent) error {
+    _, err := s.db.Exec(`INSERT INTO battle_events(battle_id, seq, type, data, created_at) VALUES(?, ?, ?, ?, ?)`,
+        battleID, e.Seq, e.Type, string(e.Data), e.CreatedAt,
+    )
+    return err
+}
+
+// FinishBattle records how and when a battle ended.
+func (s *SQLiteDB) FinishBattle(id, outcome, endedAt string) error {
+    res, err := s.db.Exec(`UPDATE battles SET outcome = ?, ended_at = ? WHERE id = ?`, outcome, endedAt, id)
+    if err != nil {
+        return err
+    }
+    affected, _ := res.RowsAffected()
+    if affected == 0 {
+        return ErrBattleNotFound
+    }
+    return nil
+}
+
+// ListBattles returns battles newest first, without their events.
+func (s *SQLiteDB) ListBattles() ([]models.Battle, error) {
+    rows, err := s.db.Query(`SELECT id, room_id, started_at, seed, ended_at, outcome FROM battles ORDER BY started_at DESC, id ASC`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    list := make([]models.Battle, 0)
+    for rows.Next() {
+        b, err := scanBattle(rows)
+        if err != nil {
+            return nil, err
+        }
+        list = append(list, b)
+    }
+    return list, rows.Err()
+}
+
+// GetBattle returns a battle with its full event log in order.
+func (s *SQLiteDB) GetBattle(id string) (models.Battle, error) {
+    b, err := scanBattle(s.db.QueryRow(`SELECT id, room_id, started_at, seed, ended_at, outcome FROM battles WHERE id = ?`, id))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return models.Battle{}, ErrBattleNotFound
+        }
+        return models.Battle{}, err
+    }
+    rows, err := s.db.Query(`SELECT seq, type, data, created_at FROM battle_events WHERE battle_id = ? ORDER BY seq ASC`, id)
+    if err != nil {
+        return models.Battle{}, err
+    }
+    defer rows.Close()
+    b.Events = make([]models.BattleEvent, 0)
+    for rows.Next() {
+        var e models.BattleEvent
+        var data string
+        if err := rows.Scan(&e.Seq, &e.Type, &data, &e.CreatedAt); err != nil {
+            return models.Battle{}, err
+        }
+        e.Data = []byte(data)
+        b.Events = append(b.Events, e)
+    }
+    return b, rows.Err()
+}
+
+type rowScanner interface {
+    Scan(dest ...any) error
+}
+
+func scanBattle(row rowScanner) (models.Battle, error) {
+    var b models.Battle
+    var endedAt, outcome sql.NullString
+    if err := row.Scan(&b.ID, &b.RoomID, &b.StartedAt, &b.Seed, &endedAt, &outcome); err != nil {
+        return models.Battle{}, err
+    }
+    if endedAt.Valid {
+        b.EndedAt = &endedAt.String
+    }
+    if outcome.Valid {
+        b.Outcome = &outcome.String
+    }
+    return b, nil
+}
 func isUniqueConstraintError(err error) bool {
     if err == nil {
         return false
@@ -0,0 +1,24 @@
+package models
+
+import "encoding/json"
+
+// Battle is one fight in a scene room, from the battle message until WIN/DEFEAT
+// (or until the GM cleans the table). Seed reproduces every roll of the battle.
+type Battle struct {
+    ID        string        `json:"id"`
+    RoomID    string        `json:"roomId"`
+    StartedAt string        `json:"startedAt"`
+    Seed      int64         `json:"seed"`
+    EndedAt   *string       `json:"endedAt,omitempty"`
+    Outcome   *string       `json:"outcome,omitempty"`
+    Events    []BattleEvent `json:"events,omitempty"`
+}
+
+// BattleEvent is an append-only log entry of a battle. Data depends on Type
+// (join, battle, vote, attack, leave, clean, end, ...).
+type BattleEvent struct {
+    Seq       int             `json:"seq"`
+    Type      string          `json:"type"`
+    Data      json.RawMessage `json:"data"`
+    CreatedAt string          `json:"createdAt"`
+}
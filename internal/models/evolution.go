@@ -0,0 +1,22 @@
+package models
+
+// EvolutionRule holds the requirements of one evolution edge (From -> To).
+// Zero values mean no requirement; Item, when set, is consumed on evolving.
+type EvolutionRule struct {
+    From     string  `json:"from"`
+    To       string  `json:"to"`
+    MinLevel int     `json:"minLevel"`
+    MinXP    int     `json:"minXp"`
+    Item     *string `json:"item,omitempty"`
+}
+
+// XPPerLevel is how much XP each user level takes.
+const XPPerLevel = 100
+
+// LevelForXP derives a user's level from their XP, starting at level 1.
+func LevelForXP(xp int) int {
+    if xp < 0 {
+        return 1
+    }
+    return xp/XPPerLevel + 1
+}
@@ -0,0 +1,10 @@
+package models
+
+// TypeEffectiveness is one cell of the elemental chart: the damage multiplier
+// applied when an attack carrying Attribute hits a defender of DefenderType.
+// Pairs missing from the chart are neutral (1x).
+type TypeEffectiveness struct {
+    Attribute    string  `json:"attribute"`
+    DefenderType string  `json:"defenderType"`
+    Multiplier   float64 `json:"multiplier"`
+}
@@ -5,15 +5,22 @@ type Attack struct {
     Name       string   `json:"name"`
     Power      int      `json:"power"`
     PP         int      `json:"pp"`
+    // Accuracy is the chance, in percent, of the attack landing before the
+    // defender's evasion.
+    Accuracy   int      `json:"accuracy"`
     Attributes []string `json:"attributes"`
 }
 
+// DefaultAccuracy is the accuracy of attacks created without one.
+const DefaultAccuracy = 100
+
 // Tyrant represents a user's monster.
 // ID is also the Tyrant's canonical name.
 type Tyrant struct {
     ID         string   `json:"id"`
     Asset      string   `json:"asset"`
     Nickname   *string  `json:"nickname,omitempty"`
+    Types      []string `json:"types"`
     Evolutions []string `json:"evolutions"`
     Attacks    []Attack `json:"attacks"`
     HP         int      `json:"hp"`
@@ -9,9 +9,11 @@ type User struct {
 }
 
 // UserItem represents an item owned by a user.
+// Quantity defaults to 1 when omitted on updates.
 type UserItem struct {
-    Name  string `json:"name"`
-    Asset string `json:"asset"`
+    Name     string `json:"name"`
+    Asset    string `json:"asset"`
+    Quantity int    `json:"quantity"`
 }
 
 // UserUpdate contains optional fields that can be updated for a user.
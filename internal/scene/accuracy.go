@@ -0,0 +1,57 @@
+package scene
+
+import (
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Evasion, in percent: evasionSpeedFactor per point of speed the defender has
+// over the attacker, up to maxEvasion. Hits keep at least minHitChance.
+const (
+	evasionSpeedFactor = 2
+	maxEvasion         = 30
+	minHitChance       = 5
+)
+
+// HitRoll is the accuracy check of one damaging hit: it lands when Roll
+// (1..100) is at most Chance, which is the attack accuracy minus the
+// defender's evasion.
+type HitRoll struct {
+	Roll     int  `json:"roll"`
+	Chance   int  `json:"chance"`
+	Accuracy int  `json:"accuracy"`
+	Evasion  int  `json:"evasion"`
+	Hit      bool `json:"hit"`
+}
+
+// attackAccuracy returns the accuracy of an attack, treating attacks stored
+// before accuracy existed as always accurate.
+func attackAccuracy(atk *models.Attack) int {
+	if atk.Accuracy <= 0 || atk.Accuracy > models.DefaultAccuracy {
+		return models.DefaultAccuracy
+	}
+	return atk.Accuracy
+}
+
+// evasion returns the defender's chance, in percent, of dodging the attacker.
+func evasion(attacker, defender *Participant) int {
+	e := (defender.Tyrant.Speed - attacker.Tyrant.Speed) * evasionSpeedFactor
+	if e < 0 {
+		return 0
+	}
+	if e > maxEvasion {
+		return maxEvasion
+	}
+	return e
+}
+
+// rollHitLocked rolls whether a damaging attack lands on the defender.
+func (h *Hub) rollHitLocked(attacker, defender *Participant, atk *models.Attack) HitRoll {
+	hr := HitRoll{Accuracy: attackAccuracy(atk), Evasion: evasion(attacker, defender)}
+	hr.Chance = hr.Accuracy - hr.Evasion
+	if hr.Chance < minHitChance {
+		hr.Chance = minHitChance
+	}
+	hr.Roll = h.rng.Intn(100) + 1
+	hr.Hit = hr.Roll <= hr.Chance
+	return hr
+}
@@ -0,0 +1,152 @@
+package scene
+
+import (
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Battle actions other than attacking, sent through "action".
+const (
+	// ActionDefend halves the damage taken until the actor's next turn.
+	ActionDefend = "defend"
+	// ActionPass ends the turn without doing anything.
+	ActionPass = "pass"
+	// ActionFlee tries to run from the battle; success ends it as FLED.
+	ActionFlee = "flee"
+	// ActionStruggle is the attack left when every attack is out of PP.
+	ActionStruggle = "struggle"
+)
+
+// struggleAttack is used by ActionStruggle: typeless, no PP, and the user
+// takes a quarter of the damage dealt as recoil.
+var struggleAttack = models.Attack{Name: "Struggle", Power: 50, Accuracy: models.DefaultAccuracy}
+
+// Flee chance, in percent: fleeBaseChance plus fleeSpeedFactor per point of
+// speed over the fastest foe, bounded by the min and max.
+const (
+	fleeBaseChance  = 50
+	fleeSpeedFactor = 5
+	fleeMinChance   = 10
+	fleeMaxChance   = 95
+)
+
+type actionEvent struct {
+	User   string `json:"user"`
+	Type   string `json:"type"`
+	Target string `json:"target,omitempty"`
+}
+
+// fleeChanceLocked returns the chance, in percent, of p running away from the
+// alive participants on the other side.
+func (h *Hub) fleeChanceLocked(p *Participant) int {
+	fastest, foes := 0, 0
+	for _, other := range h.participants {
+		if other.Alive && other.Enemy != p.Enemy {
+			foes++
+			if other.Tyrant.Speed > fastest {
+				fastest = other.Tyrant.Speed
+			}
+		}
+	}
+	if foes == 0 {
+		return 100
+	}
+	chance := fleeBaseChance + (p.Tyrant.Speed-fastest)*fleeSpeedFactor
+	if chance < fleeMinChance {
+		chance = fleeMinChance
+	}
+	if chance > fleeMaxChance {
+		chance = fleeMaxChance
+	}
+	return chance
+}
+
+// handleAction plays a non-attack action for the current actor, returning why
+// it was refused, if it was.
+func (h *Hub) handleAction(ev actionEvent) *errorMessage {
+	fail := func(msg string) *errorMessage {
+		h.mu.Unlock()
+		return &errorMessage{Error: msg}
+	}
+	h.mu.Lock()
+	if !h.inBattle {
+		return fail("not in battle")
+	}
+	p := h.participants[ev.User]
+	if p == nil || !p.Alive {
+		return fail("invalid user")
+	}
+	if h.currentActor != "" && h.currentActor != ev.User {
+		h.mu.Unlock()
+		return &errorMessage{Error: "not your turn", Expected: h.currentActor}
+	}
+
+	lastAction := actionResult{attackResult: attackResult{User: ev.User}, Type: ev.Type}
+	logData := map[string]any{"user": ev.User, "type": ev.Type}
+	var events []statusEvent
+	switch ev.Type {
+	case ActionDefend:
+		p.Defending = true
+	case ActionPass:
+	case ActionFlee:
+		if p.Enemy {
+			return fail("only allies can flee")
+		}
+		chance := h.fleeChanceLocked(p)
+		roll := h.rng.Intn(100)
+		fled := roll < chance
+		lastAction.Fled = &fled
+		logData["chance"] = chance
+		logData["roll"] = roll
+		logData["fled"] = fled
+		if fled {
+			h.logEventLocked("action", logData)
+			result := h.finishBattleLocked("FLED")
+			result.LastAction = &lastAction
+			payload := h.updateLocked(result)
+			h.mu.Unlock()
+			h.broadcast(payload)
+			return nil
+		}
+	case ActionStruggle:
+		if len(usableAttacks(p)) > 0 {
+			return fail("attacks still have PP")
+		}
+		target := h.participants[ev.Target]
+		if target == nil || !target.Alive || target.Enemy == p.Enemy {
+			return fail("invalid target")
+		}
+		atk := struggleAttack
+		lastAttack, attackLog, strikeEvents := h.strikeLocked(ev.User, []string{ev.Target}, p, &atk)
+		events = strikeEvents
+		lastAction.attackResult = lastAttack
+		for k, v := range attackLog {
+			logData[k] = v
+		}
+		logData["type"] = ev.Type
+		dmg, _ := attackLog["damage"].(int)
+		if dmg > 0 && !lastAttack.Confused {
+			recoil := dmg / 4
+			if recoil < 1 {
+				recoil = 1
+			}
+			h.damageLocked(p, recoil)
+			lastAction.Recoil = recoil
+			logData["recoil"] = recoil
+		}
+	default:
+		return fail("unknown action")
+	}
+	logData["statusEvents"] = events
+	h.logEventLocked("action", logData)
+	status, events := h.endTurnLocked(ev.User, events)
+	if status == nil {
+		state := h.stateLocked(events)
+		state.LastAction = &lastAction
+		status = &state
+	}
+	payload := h.updateLocked(*status)
+	h.mu.Unlock()
+
+	h.broadcast(payload)
+	return nil
+}
@@ -0,0 +1,290 @@
+package scene
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Built-in enemy strategies, picked per participant through "ai" on join.
+const (
+	AIRandom     = "random"
+	AILowestHP   = "lowestHp"
+	AIEffective  = "effective"
+	AIConservePP = "conservePP"
+)
+
+// DefaultAIDelay is how long a computer-controlled participant waits before
+// acting, so clients have time to animate the previous turn.
+const DefaultAIDelay = 1500 * time.Millisecond
+
+// AIInput is what a strategy sees when choosing its move.
+type AIInput struct {
+	Self *Participant
+	// Foes are the alive participants on the other side, sorted by id.
+	Foes      []AIFoe
+	TypeChart func(attrs, defTypes []string) float64
+	Rand      Rand
+}
+
+// AIFoe is a possible target.
+type AIFoe struct {
+	ID string
+	*Participant
+}
+
+// AIChoice is the attack a strategy decided on.
+type AIChoice struct {
+	Attack string
+	Target string
+}
+
+// EnemyStrategy picks the move of a computer-controlled participant. ok is
+// false when it has nothing to do: out of PP it struggles, otherwise the turn
+// is skipped.
+type EnemyStrategy interface {
+	Choose(in AIInput) (choice AIChoice, ok bool)
+}
+
+var enemyStrategies = map[string]EnemyStrategy{
+	AIRandom:     randomStrategy{},
+	AILowestHP:   lowestHPStrategy{},
+	AIEffective:  effectiveStrategy{},
+	AIConservePP: conservePPStrategy{},
+}
+
+// usableAttacks returns the attacks that still have PP, in the Tyrant's order.
+func usableAttacks(p *Participant) []string {
+	var out []string
+	for _, atk := range p.Tyrant.Attacks {
+		if pp := p.AttackPP[atk.Name]; pp != nil && pp.Current > 0 {
+			out = append(out, atk.Name)
+		}
+	}
+	return out
+}
+
+// legalFoes returns the foes atk may target, in the order of in.Foes.
+func legalFoes(in AIInput, atk *models.Attack) []AIFoe {
+	move := parseMove(atk)
+	var out []AIFoe
+	for _, f := range in.Foes {
+		if move.legalTarget(in.Self.ID, f.ID, in.Self, f.Participant) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// damagingAttacks returns the usable attacks that deal damage to at least one
+// foe; strategies leave support moves to the players.
+func damagingAttacks(in AIInput) []string {
+	var out []string
+	for i := range in.Self.Tyrant.Attacks {
+		atk := &in.Self.Tyrant.Attacks[i]
+		pp := in.Self.AttackPP[atk.Name]
+		if pp != nil && pp.Current > 0 && parseMove(atk).category == MoveDamage && len(legalFoes(in, atk)) > 0 {
+			out = append(out, atk.Name)
+		}
+	}
+	return out
+}
+
+// targetsFor returns the foes the named attack of in.Self may target.
+func targetsFor(in AIInput, name string) []AIFoe {
+	for i := range in.Self.Tyrant.Attacks {
+		if atk := &in.Self.Tyrant.Attacks[i]; atk.Name == name {
+			return legalFoes(in, atk)
+		}
+	}
+	return nil
+}
+
+func attackPower(p *Participant, name string) int {
+	for _, atk := range p.Tyrant.Attacks {
+		if atk.Name == name {
+			return atk.Power
+		}
+	}
+	return 0
+}
+
+// strongestAttack returns the usable attack with the highest power.
+func strongestAttack(p *Participant, usable []string) string {
+	best := usable[0]
+	for _, name := range usable[1:] {
+		if attackPower(p, name) > attackPower(p, best) {
+			best = name
+		}
+	}
+	return best
+}
+
+// weakestFoe returns the foe with the lowest current HP.
+func weakestFoe(foes []AIFoe) string {
+	best := foes[0]
+	for _, f := range foes[1:] {
+		if f.CurrentHP < best.CurrentHP {
+			best = f
+		}
+	}
+	return best.ID
+}
+
+// randomStrategy uses a random attack on a random foe.
+type randomStrategy struct{}
+
+func (randomStrategy) Choose(in AIInput) (AIChoice, bool) {
+	usable := damagingAttacks(in)
+	if len(usable) == 0 {
+		return AIChoice{}, false
+	}
+	attack := usable[in.Rand.Intn(len(usable))]
+	foes := targetsFor(in, attack)
+	return AIChoice{Attack: attack, Target: foes[in.Rand.Intn(len(foes))].ID}, true
+}
+
+// lowestHPStrategy focuses the weakest foe with the strongest attack.
+type lowestHPStrategy struct{}
+
+func (lowestHPStrategy) Choose(in AIInput) (AIChoice, bool) {
+	usable := damagingAttacks(in)
+	if len(usable) == 0 {
+		return AIChoice{}, false
+	}
+	attack := strongestAttack(in.Self, usable)
+	return AIChoice{Attack: attack, Target: weakestFoe(targetsFor(in, attack))}, true
+}
+
+// effectiveStrategy picks the attack and foe with the highest power times
+// type effectiveness, weighted by the attack accuracy.
+type effectiveStrategy struct{}
+
+func (effectiveStrategy) Choose(in AIInput) (AIChoice, bool) {
+	usable := damagingAttacks(in)
+	if len(usable) == 0 {
+		return AIChoice{}, false
+	}
+	var best AIChoice
+	bestScore := -1.0
+	for _, atk := range in.Self.Tyrant.Attacks {
+		if pp := in.Self.AttackPP[atk.Name]; pp == nil || pp.Current <= 0 || parseMove(&atk).category != MoveDamage {
+			continue
+		}
+		for _, f := range legalFoes(in, &atk) {
+			score := float64(atk.Power*attackAccuracy(&atk)) * in.TypeChart(atk.Attributes, f.Tyrant.Types)
+			if score > bestScore {
+				best, bestScore = AIChoice{Attack: atk.Name, Target: f.ID}, score
+			}
+		}
+	}
+	return best, true
+}
+
+// conservePPStrategy spreads PP usage by picking the attack with the largest
+// share of PP left, on the weakest foe.
+type conservePPStrategy struct{}
+
+func (conservePPStrategy) Choose(in AIInput) (AIChoice, bool) {
+	usable := damagingAttacks(in)
+	if len(usable) == 0 {
+		return AIChoice{}, false
+	}
+	share := func(name string) float64 {
+		pp := in.Self.AttackPP[name]
+		if pp.Full <= 0 {
+			return 0
+		}
+		return float64(pp.Current) / float64(pp.Full)
+	}
+	best := usable[0]
+	for _, name := range usable[1:] {
+		if share(name) > share(best) {
+			best = name
+		}
+	}
+	return AIChoice{Attack: best, Target: weakestFoe(targetsFor(in, best))}, true
+}
+
+// aiInputLocked gathers what the strategy of id may look at.
+func (h *Hub) aiInputLocked(id string) AIInput {
+	self := h.participants[id]
+	in := AIInput{Self: self, TypeChart: h.typeChart.multiplier, Rand: h.rng}
+	for fid, p := range h.participants {
+		if p.Alive && p.Enemy != self.Enemy {
+			in.Foes = append(in.Foes, AIFoe{ID: fid, Participant: p})
+		}
+	}
+	sort.Slice(in.Foes, func(i, j int) bool { return in.Foes[i].ID < in.Foes[j].ID })
+	return in
+}
+
+// aiTurn plays the turn of a computer-controlled participant.
+func (h *Hub) aiTurn(gen uint64) {
+	h.mu.Lock()
+	if gen != h.turnGen || !h.inBattle {
+		h.mu.Unlock()
+		return
+	}
+	if len(h.clients) == 0 {
+		// nobody at the table to watch it: wait for them
+		h.armTurnTimerLocked()
+		h.mu.Unlock()
+		return
+	}
+	actor := h.currentActor
+	p := h.participants[actor]
+	if p == nil || p.AI == "" {
+		h.mu.Unlock()
+		return
+	}
+	in := h.aiInputLocked(actor)
+	choice, ok := enemyStrategies[p.AI].Choose(in)
+	if !ok && len(usableAttacks(p)) == 0 && len(in.Foes) > 0 {
+		// out of PP: struggle rather than stall
+		h.mu.Unlock()
+		if failure := h.handleAction(actionEvent{User: actor, Type: ActionStruggle, Target: weakestFoe(in.Foes)}); failure != nil {
+			h.skipRefusedAITurn(gen, actor, failure)
+		}
+		h.persist()
+		return
+	}
+	if !ok {
+		payload := h.skipTurnLocked(actor, "skip")
+		payload.TurnSkipped = actor
+		h.mu.Unlock()
+		h.broadcast(payload)
+		h.persist()
+		return
+	}
+	ev := attackEvent{User: actor, Target: choice.Target, Attack: choice.Attack}
+	for i := range p.Tyrant.Attacks {
+		if atk := &p.Tyrant.Attacks[i]; atk.Name == choice.Attack && parseMove(atk).area != AreaSingle {
+			// area attacks hit every legal target on their own
+			ev.Target = ""
+		}
+	}
+	h.mu.Unlock()
+
+	if failure := h.handleAttack(ev); failure != nil {
+		h.skipRefusedAITurn(gen, actor, failure)
+	}
+	h.persist()
+}
+
+// skipRefusedAITurn passes the turn of a computer-controlled participant whose
+// move the rules refused, so the battle never waits on it.
+func (h *Hub) skipRefusedAITurn(gen uint64, actor string, failure *errorMessage) {
+	h.mu.Lock()
+	if gen != h.turnGen || !h.inBattle || h.currentActor != actor {
+		h.mu.Unlock()
+		return
+	}
+	log.Printf("scene: %s move refused (%s), skipping its turn", actor, failure.Error)
+	payload := h.skipTurnLocked(actor, "skip")
+	payload.TurnSkipped = actor
+	h.mu.Unlock()
+	h.broadcast(payload)
+}
@@ -0,0 +1,88 @@
+package scene
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+func newBattleID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func nowStamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// startBattleLogLocked opens a new battle record and logs everyone already
+// at the table as joined.
+func (h *Hub) startBattleLogLocked(startWith string, voteEnabled bool) {
+	if h.battleID != "" {
+		// battle restarted before the previous one finished
+		h.endBattleLogLocked("ABANDONED")
+	}
+	b := models.Battle{ID: newBattleID(), RoomID: h.id, StartedAt: nowStamp(), Seed: h.rng.seed}
+	h.battleID = b.ID
+	h.eventSeq = 0
+	h.logOps = append(h.logOps, func() error { return h.svc.CreateBattle(b) })
+	for _, id := range h.turnOrder {
+		p := h.participants[id]
+		h.logEventLocked("join", map[string]any{"id": id, "tyrant": p.Tyrant.ID, "name": p.displayName(), "enemy": p.Enemy, "level": p.level(), "fullHp": p.FullHP})
+	}
+	h.logEventLocked("battle", map[string]any{"startWith": startWith, "voteEnabled": voteEnabled, "seed": h.rng.seed, "rules": h.rules, "turnTimeout": int(h.turnTimeout / time.Second)})
+}
+
+// logEventLocked queues an event for the current battle, if any.
+func (h *Hub) logEventLocked(typ string, data any) {
+	if h.battleID == "" {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("scene: encode battle event %s: %v", typ, err)
+		return
+	}
+	h.eventSeq++
+	battleID := h.battleID
+	e := models.BattleEvent{Seq: h.eventSeq, Type: typ, Data: raw, CreatedAt: nowStamp()}
+	h.logOps = append(h.logOps, func() error { return h.svc.AppendBattleEvent(battleID, e) })
+}
+
+// endBattleLogLocked closes the current battle with its outcome.
+func (h *Hub) endBattleLogLocked(outcome string) {
+	if h.battleID == "" {
+		return
+	}
+	h.logEventLocked("end", map[string]any{"outcome": outcome})
+	battleID, endedAt := h.battleID, nowStamp()
+	h.logOps = append(h.logOps, func() error { return h.svc.FinishBattle(battleID, outcome, endedAt) })
+	h.battleID = ""
+}
+
+// flushBattleLog writes queued log operations in the order they were queued.
+func (h *Hub) flushBattleLog() {
+	h.logMu.Lock()
+	defer h.logMu.Unlock()
+	h.mu.Lock()
+	ops := h.logOps
+	h.logOps = nil
+	h.mu.Unlock()
+	for _, op := range ops {
+		if err := op(); err != nil {
+			log.Printf("scene: write battle log %s: %v", h.id, err)
+		}
+	}
+}
+
+// persist flushes the battle log and owner updates, then checkpoints the room.
+func (h *Hub) persist() {
+	h.flushBattleLog()
+	h.flushUserOps()
+	h.checkpoint()
+}
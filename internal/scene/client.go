@@ -0,0 +1,142 @@
+package scene
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait bounds every write to a client, pings included.
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent (no message and no pong)
+	// before it is considered dead and disconnected.
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait so live clients always answer in time.
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize limits incoming messages. Scene images may be data URLs,
+	// so it leaves room for a few MiB of base64; anything larger closes the
+	// connection with 1009 (message too big).
+	maxMessageSize = 8 << 20
+	// sendQueueSize is how many outgoing messages a client may have pending.
+	// A client that falls this far behind is disconnected rather than slowing
+	// the room down or silently missing state updates.
+	sendQueueSize = 64
+)
+
+var (
+	errClientClosed = errors.New("client closed")
+	errQueueFull    = errors.New("client send queue full")
+)
+
+// Client is one websocket connection to a room. Every write goes through its
+// send queue and is done by its own writer goroutine, so handlers never block
+// on a slow socket and a connection is never written concurrently.
+type Client struct {
+	conn *websocket.Conn
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+	// role and user authenticated on upgrade; user is empty for spectators
+	role string
+	user string
+	// version is the negotiated protocol version; 0 is the legacy flat format
+	version int
+}
+
+// newClient wraps conn and starts its writer goroutine.
+func newClient(conn *websocket.Conn, role, user string, version int) *Client {
+	c := &Client{
+		conn:    conn,
+		send:    make(chan []byte, sendQueueSize),
+		done:    make(chan struct{}),
+		role:    role,
+		user:    user,
+		version: version,
+	}
+	go c.writePump()
+	return c
+}
+
+// writeMessage queues m for the client in its protocol version. It never
+// blocks: when the queue is full the client is disconnected and errQueueFull
+// returned.
+func (c *Client) writeMessage(m serverMessage) error {
+	data, err := encodeMessage(m, c.version)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(data)
+}
+
+// enqueue queues an already encoded message, disconnecting the client on overflow.
+func (c *Client) enqueue(data []byte) error {
+	select {
+	case <-c.done:
+		return errClientClosed
+	default:
+	}
+	select {
+	case c.send <- data:
+		return nil
+	default:
+		log.Printf("scene: client %s send queue full, disconnecting", c.conn.RemoteAddr())
+		c.close()
+		return errQueueFull
+	}
+}
+
+// close stops the writer and closes the connection, which also ends the read
+// loop in ServeWS. It is safe to call more than once.
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// readLoop hands every incoming message to handle until the connection fails
+// or stays silent past pongWait.
+func (c *Client) readLoop(handle func([]byte)) {
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		handle(data)
+	}
+}
+
+// writePump writes queued messages and periodic pings, each with a deadline.
+// A failed write closes the client.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scene
+
+import (
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Built-in rule sets, picked per battle through "rules" in the battle message.
+const (
+	RulesClassic = "classic"
+	RulesLeveled = "leveled"
+	RulesStory   = "story"
+)
+
+// DefaultLevel is the level of a participant that joins without one.
+const DefaultLevel = 1
+
+// DamageInput is everything a rule set sees when resolving one hit.
+type DamageInput struct {
+	Attacker *Participant
+	Defender *Participant
+	Attack   *models.Attack
+	// Effectiveness is the type chart multiplier of the attack vs the defender.
+	Effectiveness float64
+	Rand          Rand
+}
+
+// DamageModifier is one multiplier applied on top of the base damage.
+type DamageModifier struct {
+	Name   string  `json:"name"`
+	Factor float64 `json:"factor"`
+}
+
+// DamageResult is the breakdown of a resolved hit.
+type DamageResult struct {
+	Roll      int              `json:"roll"`
+	Base      int              `json:"base"`
+	Crit      bool             `json:"crit"`
+	Modifiers []DamageModifier `json:"modifiers"`
+	Damage    int              `json:"damage"`
+}
+
+// DamageCalculator resolves the damage of an attack under one rule set.
+type DamageCalculator interface {
+	Calculate(in DamageInput) DamageResult
+}
+
+var damageRules = map[string]DamageCalculator{
+	RulesClassic: classicDamage{},
+	RulesLeveled: leveledDamage{},
+	RulesStory:   storyDamage{},
+}
+
+// damageCalculator returns the rule set registered under name; empty means classic.
+func damageCalculator(name string) (DamageCalculator, bool) {
+	if name == "" {
+		name = RulesClassic
+	}
+	calc, ok := damageRules[name]
+	return calc, ok
+}
+
+// classicDamage is the original table formula: a 1..100 roll added to ten
+// times the attack power, scaled by attack and reduced by defense. Rolls of
+// 90 or more are critical hits.
+type classicDamage struct{}
+
+func (classicDamage) Calculate(in DamageInput) DamageResult {
+	roll := in.Rand.Intn(100) + 1
+	base := (in.Attacker.Tyrant.Attack*(roll+in.Attack.Power*10) - in.Defender.Tyrant.Defense) / 200
+	if base < 1 {
+		base = 1
+	}
+	res := DamageResult{Roll: roll, Base: base, Crit: roll >= 90, Modifiers: []DamageModifier{}}
+	if res.Crit {
+		res.Modifiers = append(res.Modifiers, DamageModifier{Name: "crit", Factor: 2})
+	}
+	res.Modifiers = append(res.Modifiers, commonModifiers(in)...)
+	res.Damage = applyModifiers(base, res.Modifiers, in.Effectiveness)
+	return res
+}
+
+// leveledDamage is the classic formula scaled by the level gap between the
+// attacker and the defender: 10% more damage per level above, 10% less per
+// level below, never under a quarter of the classic result.
+type leveledDamage struct{}
+
+func (leveledDamage) Calculate(in DamageInput) DamageResult {
+	res := classicDamage{}.Calculate(in)
+	factor := 1 + float64(in.Attacker.level()-in.Defender.level())/10
+	if factor < 0.25 {
+		factor = 0.25
+	}
+	if factor != 1 {
+		res.Modifiers = append(res.Modifiers, DamageModifier{Name: "level", Factor: factor})
+		res.Damage = applyModifiers(res.Base, res.Modifiers, in.Effectiveness)
+	}
+	return res
+}
+
+// storyDamage ignores stats and dice: every hit deals a tenth of the attack
+// power, so the GM controls the pace of the fight through the attacks alone.
+type storyDamage struct{}
+
+func (storyDamage) Calculate(in DamageInput) DamageResult {
+	base := in.Attack.Power / 10
+	if base < 1 {
+		base = 1
+	}
+	res := DamageResult{Base: base, Modifiers: []DamageModifier{}}
+	if in.Effectiveness != 1 {
+		res.Modifiers = append(res.Modifiers, DamageModifier{Name: "type", Factor: in.Effectiveness})
+	}
+	res.Damage = applyModifiers(base, res.Modifiers, in.Effectiveness)
+	return res
+}
+
+// commonModifiers are the status and type modifiers shared by the stat based rule sets.
+func commonModifiers(in DamageInput) []DamageModifier {
+	var mods []DamageModifier
+	// burned attackers deal half damage
+	if in.Attacker.hasStatus(statusBurn) {
+		mods = append(mods, DamageModifier{Name: statusBurn, Factor: 0.5})
+	}
+	if in.Effectiveness != 1 {
+		mods = append(mods, DamageModifier{Name: "type", Factor: in.Effectiveness})
+	}
+	return mods
+}
+
+// applyModifiers multiplies the base damage by every modifier, rounding down
+// after each step. A hit always deals at least 1 unless the type chart says
+// the attack has no effect.
+func applyModifiers(base int, mods []DamageModifier, effectiveness float64) int {
+	damage := base
+	for _, m := range mods {
+		damage = int(float64(damage) * m.Factor)
+	}
+	if damage < 1 && effectiveness > 0 {
+		damage = 1
+	}
+	return damage
+}
+
+func (p *Participant) level() int {
+	if p.Level < 1 {
+		return DefaultLevel
+	}
+	return p.Level
+}
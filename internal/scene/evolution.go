@@ -0,0 +1,138 @@
+package scene
+
+import (
+	"log"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// NotifyEvolution tells every room that user's Tyrant evolved from one species
+// to another, swapping the user's combatants of that species in place.
+func (r *Registry) NotifyEvolution(user, from, to string) {
+	t, err := r.svc.GetTyrant(to)
+	if err != nil {
+		log.Printf("scene: evolution of %s to %s: %v", user, to, err)
+		return
+	}
+	r.mu.Lock()
+	hubs := make([]*Hub, 0, len(r.rooms))
+	for _, h := range r.rooms {
+		hubs = append(hubs, h)
+	}
+	r.mu.Unlock()
+	for _, h := range hubs {
+		h.evolve(user, from, t)
+	}
+}
+
+// evolve replaces every combatant of species from owned by user with the
+// evolved Tyrant. HP keeps its ratio, PP carries over for attacks with the
+// same name, and statuses, level, control and XP contribution stay as they
+// were. The combatant gets an id of the new species.
+func (h *Hub) evolve(user, from string, t models.Tyrant) {
+	h.mu.Lock()
+	if from == t.ID {
+		h.mu.Unlock()
+		return
+	}
+	var ids []string
+	for _, enemy := range []bool{false, true} {
+		ids = append(ids, h.ownedInstancesLocked(from, user, enemy)...)
+	}
+	if len(ids) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	payloads := make([]evolvedInfo, 0, len(ids))
+	for _, id := range ids {
+		newID, p := h.evolveInstanceLocked(id, t)
+		h.logEventLocked("evolve", map[string]any{"user": user, "from": id, "to": newID, "tyrant": t.ID, "fullHp": p.FullHP, "currentHp": p.CurrentHP})
+		payloads = append(payloads, evolvedInfo{User: user, From: id, To: newID, Tyrant: t.ID, Name: p.displayName(), Asset: t.Asset})
+	}
+	if !h.inBattle && !h.votingActive {
+		h.computeTurnOrderLocked()
+	}
+	state := h.stateLocked(nil)
+	turns := h.turnsViewLocked()
+	msgs := make([]evolvedMessage, 0, len(payloads))
+	for _, evolved := range payloads {
+		msgs = append(msgs, evolvedMessage{Evolved: evolved, UpdateState: state, Turns: turns, TurnDeadline: h.turnDeadlineLocked()})
+	}
+	h.mu.Unlock()
+
+	for _, msg := range msgs {
+		h.broadcast(msg)
+	}
+	h.persist()
+}
+
+// evolveInstanceLocked swaps the combatant id for a participant of the
+// evolved Tyrant, keeping its place in the queue, and returns the new id.
+func (h *Hub) evolveInstanceLocked(from string, t models.Tyrant) (string, *Participant) {
+	old := h.participants[from]
+	delete(h.participants, from)
+	to := h.nextInstanceIDLocked(t.ID)
+	name := old.Name
+	if name == "" || name == defaultInstanceName(old.Tyrant, from) {
+		name = defaultInstanceName(t, to)
+	}
+	p := &Participant{
+		ID:          to,
+		Name:        name,
+		Tyrant:      t,
+		Enemy:       old.Enemy,
+		Level:       old.Level,
+		FullHP:      t.HP,
+		Alive:       old.Alive,
+		Statuses:    old.Statuses,
+		Owner:       old.Owner,
+		AI:          old.AI,
+		DamageDealt: old.DamageDealt,
+		Defending:   old.Defending,
+		Buffs:       old.Buffs,
+		AttackPP: make(map[string]*struct {
+			Full    int
+			Current int
+		}),
+	}
+	if old.Alive && old.FullHP > 0 {
+		p.CurrentHP = t.HP * old.CurrentHP / old.FullHP
+		if p.CurrentHP < 1 {
+			p.CurrentHP = 1
+		}
+	}
+	for _, atk := range t.Attacks {
+		cur := atk.PP
+		if pp := old.AttackPP[atk.Name]; pp != nil && pp.Current < cur {
+			cur = pp.Current
+		}
+		p.AttackPP[atk.Name] = &struct {
+			Full    int
+			Current int
+		}{Full: atk.PP, Current: cur}
+	}
+
+	h.participants[to] = p
+	if c, ok := h.tyrantIDToClient[from]; ok {
+		delete(h.tyrantIDToClient, from)
+		h.tyrantIDToClient[to] = c
+	}
+	h.renameSessionLocked(from, to)
+	if v, ok := h.votedAllies[from]; ok {
+		delete(h.votedAllies, from)
+		h.votedAllies[to] = v
+	}
+	if h.currentActor == from {
+		h.currentActor = to
+	}
+	if h.battleStartedWith == from {
+		h.battleStartedWith = to
+	}
+	// keep the position in the queue
+	for i, id := range h.turnOrder {
+		if id == from {
+			h.turnOrder[i] = to
+		}
+	}
+	return to, p
+}
@@ -0,0 +1,88 @@
+package scene
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// instanceSep separates the species from the instance number in the id of a
+// combatant: the first "tumba" at the table is "tumba", the next "tumba#2".
+const instanceSep = "#"
+
+// nextInstanceIDLocked returns a free combatant id for a Tyrant species.
+func (h *Hub) nextInstanceIDLocked(species string) string {
+	if _, taken := h.participants[species]; !taken {
+		return species
+	}
+	for n := 2; ; n++ {
+		id := species + instanceSep + strconv.Itoa(n)
+		if _, taken := h.participants[id]; !taken {
+			return id
+		}
+	}
+}
+
+// instanceNumber splits a combatant id into its species and instance number;
+// the first instance, without a suffix, is number 1.
+func instanceNumber(id string) (string, int) {
+	species, suffix, ok := strings.Cut(id, instanceSep)
+	if !ok {
+		return id, 1
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil {
+		return id, 1
+	}
+	return species, n
+}
+
+// lessInstance orders combatant ids by species, then by instance number, so
+// "tumba#2" comes before "tumba#10".
+func lessInstance(a, b string) bool {
+	sa, na := instanceNumber(a)
+	sb, nb := instanceNumber(b)
+	if sa != sb {
+		return sa < sb
+	}
+	if na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+// defaultInstanceName is the display name of a combatant without a custom
+// one: the Tyrant's nickname (or id), plus the instance number if any.
+func defaultInstanceName(t models.Tyrant, id string) string {
+	name := t.ID
+	if t.Nickname != nil && *t.Nickname != "" {
+		name = *t.Nickname
+	}
+	if _, n, ok := strings.Cut(id, instanceSep); ok {
+		name += " " + instanceSep + n
+	}
+	return name
+}
+
+// displayName is how clients show the participant.
+func (p *Participant) displayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.ID
+}
+
+// ownedInstancesLocked lists, in a stable order, the combatants of a species
+// owned by user on the given side.
+func (h *Hub) ownedInstancesLocked(species, user string, enemy bool) []string {
+	var ids []string
+	for id, p := range h.participants {
+		if p.Tyrant.ID == species && p.Owner == user && p.Enemy == enemy {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return lessInstance(ids[i], ids[j]) })
+	return ids
+}
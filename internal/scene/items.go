@@ -0,0 +1,186 @@
+package scene
+
+import (
+	"errors"
+
+	"github.com/matheustorresii/tyrants-back/internal/db"
+)
+
+// itemDef is the battle effect of an inventory item. Items are matched to the
+// user's inventory by name.
+type itemDef struct {
+	heal      int      // HP restored
+	restorePP int      // PP restored to every attack
+	cure      []string // conditions removed; nil cures none
+	cureAll   bool     // removes every condition
+	revive    int      // % of max HP a fainted target comes back with
+}
+
+var itemCatalog = map[string]itemDef{
+	"potion":        {heal: 20},
+	"super-potion":  {heal: 50},
+	"hyper-potion":  {heal: 120},
+	"ether":         {restorePP: 10},
+	"antidote":      {cure: []string{statusPoison}},
+	"burn-heal":     {cure: []string{statusBurn}},
+	"awakening":     {cure: []string{statusSleep}},
+	"paralyze-heal": {cure: []string{statusParalysis}},
+	"full-heal":     {cureAll: true},
+	"revive":        {revive: 50},
+	"max-revive":    {revive: 100},
+}
+
+// itemEffect is what an item did to its target.
+type itemEffect struct {
+	Healed     int `json:"healed,omitempty"`
+	PPRestored int `json:"ppRestored,omitempty"`
+	Revived    int `json:"revived,omitempty"`
+	// StatusEvents are the conditions cured
+	StatusEvents []statusEvent `json:"-"`
+}
+
+// empty reports whether the item would do nothing.
+func (e itemEffect) empty() bool {
+	return e.Healed == 0 && e.PPRestored == 0 && e.Revived == 0 && len(e.StatusEvents) == 0
+}
+
+type itemEvent struct {
+	User   string `json:"user"`
+	Item   string `json:"item"`
+	Target string `json:"target,omitempty"`
+}
+
+// applyItem reports what the item would do to the target, changing it only
+// when apply is true. An empty result means the item has no effect.
+func (def itemDef) applyItem(targetID string, p *Participant, apply bool) itemEffect {
+	var out itemEffect
+	if def.revive > 0 {
+		if p.Alive {
+			return out
+		}
+		hp := p.FullHP * def.revive / 100
+		if hp < 1 {
+			hp = 1
+		}
+		if apply {
+			p.Alive = true
+			p.CurrentHP = hp
+		}
+		out.Revived = hp
+		return out
+	}
+	if !p.Alive {
+		return out
+	}
+	if def.heal > 0 && p.CurrentHP < p.FullHP {
+		healed := def.heal
+		if p.CurrentHP+healed > p.FullHP {
+			healed = p.FullHP - p.CurrentHP
+		}
+		if apply {
+			p.CurrentHP += healed
+		}
+		out.Healed = healed
+	}
+	if def.restorePP > 0 {
+		restored := 0
+		for _, pp := range p.AttackPP {
+			n := def.restorePP
+			if pp.Current+n > pp.Full {
+				n = pp.Full - pp.Current
+			}
+			if apply {
+				pp.Current += n
+			}
+			restored += n
+		}
+		out.PPRestored = restored
+	}
+	for _, name := range p.statusNames() {
+		if !def.cureAll && !containsString(def.cure, name) {
+			continue
+		}
+		if apply {
+			delete(p.Statuses, name)
+		}
+		out.StatusEvents = append(out.StatusEvents, statusEvent{ID: targetID, Status: name, Event: "cured"})
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// handleItem lets the current actor spend its turn using an item from its
+// owner's inventory on itself or an ally.
+func (h *Hub) handleItem(ev itemEvent) *errorMessage {
+	if ev.Target == "" {
+		ev.Target = ev.User
+	}
+	fail := func(msg string) *errorMessage {
+		h.mu.Unlock()
+		return &errorMessage{Error: msg}
+	}
+	h.mu.Lock()
+	if !h.inBattle {
+		return fail("not in battle")
+	}
+	user := h.participants[ev.User]
+	target := h.participants[ev.Target]
+	if user == nil || target == nil || !user.Alive {
+		return fail("invalid user or target")
+	}
+	if h.currentActor != "" && h.currentActor != ev.User {
+		h.mu.Unlock()
+		return &errorMessage{Error: "not your turn", Expected: h.currentActor}
+	}
+	if target.Enemy != user.Enemy {
+		return fail("items can only target allies")
+	}
+	def, ok := itemCatalog[ev.Item]
+	if !ok {
+		return fail("unknown item")
+	}
+	if user.Owner == "" {
+		return fail("no inventory for this tyrant")
+	}
+	if def.revive > 0 && h.lostLocked(target) {
+		return fail("target lost")
+	}
+	if def.applyItem(ev.Target, target, false).empty() {
+		return fail("item has no effect")
+	}
+	// consumed under the hub lock so the turn cannot move on in between
+	remaining, err := h.svc.ConsumeUserItem(user.Owner, ev.Item)
+	if err != nil {
+		if errors.Is(err, db.ErrUserItemNotFound) {
+			return fail("item not in inventory")
+		}
+		return fail("could not use item")
+	}
+	effect := def.applyItem(ev.Target, target, true)
+	events := effect.StatusEvents
+	lastItem := itemResult{User: ev.User, Target: ev.Target, Item: ev.Item, Remaining: remaining, itemEffect: effect}
+	h.logEventLocked("item", struct {
+		Owner string `json:"owner"`
+		itemResult
+		StatusEvents []statusEvent `json:"statusEvents"`
+	}{user.Owner, lastItem, events})
+	status, events := h.endTurnLocked(ev.User, events)
+	if status == nil {
+		state := h.stateLocked(events)
+		state.LastItem = &lastItem
+		status = &state
+	}
+	payload := h.updateLocked(*status)
+	h.mu.Unlock()
+
+	h.broadcast(payload)
+	return nil
+}
@@ -0,0 +1,155 @@
+package scene
+
+// serverMessage is a message the scene sends to clients. Legacy clients get
+// its fields at the top level; v1 clients get it as the payload of an
+// envelope of its type.
+type serverMessage interface {
+	messageType() string
+}
+
+// connectedMessage greets a new connection with its role and protocol version.
+type connectedMessage struct {
+	Connected connectedInfo `json:"connected"`
+}
+
+type connectedInfo struct {
+	Role    string `json:"role"`
+	User    string `json:"user,omitempty"`
+	Version int    `json:"v"`
+}
+
+// errorMessage tells the sender why its message was refused.
+type errorMessage struct {
+	Error string `json:"error"`
+	// ID is the request id of the refused message, if it had one
+	ID string `json:"id,omitempty"`
+	// Expected is whose turn it is, for "not your turn"
+	Expected string `json:"expected,omitempty"`
+	// Struggle is set when no attack has PP left
+	Struggle bool `json:"struggle,omitempty"`
+	// Category, Targets and Area describe the move of a refused target
+	Category string `json:"category,omitempty"`
+	Targets  string `json:"targets,omitempty"`
+	Area     string `json:"area,omitempty"`
+}
+
+// ackMessage confirms the request with id Ack was carried out.
+type ackMessage struct {
+	Ack string `json:"ack"`
+}
+
+// sessionMessage hands the joining client its reconnect token.
+type sessionMessage struct {
+	Session sessionInfo `json:"session"`
+}
+
+type sessionInfo struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// resumedMessage confirms a resume with the full state of the room.
+type resumedMessage struct {
+	Resumed string     `json:"resumed"`
+	State   sceneState `json:"state"`
+}
+
+// syncMessage is the full state of the room, sent on connect and on request.
+type syncMessage struct {
+	Sync sceneState `json:"sync"`
+}
+
+// imageMessage is the scene image shown to everyone.
+type imageMessage struct {
+	Image string `json:"image"`
+	Fill  *bool  `json:"fill,omitempty"`
+}
+
+// joinedMessage announces a combatant joining (or being taken over).
+type joinedMessage struct {
+	Joined string     `json:"joined"`
+	Tyrant string     `json:"tyrant"`
+	Name   string     `json:"name"`
+	Enemy  bool       `json:"enemy"`
+	AI     string     `json:"ai,omitempty"`
+	Turns  []turnView `json:"turns"`
+}
+
+// leftMessage announces an ally leaving the table.
+type leftMessage struct {
+	Left         string        `json:"left"`
+	Turns        []turnView    `json:"turns"`
+	StatusEvents []statusEvent `json:"statusEvents,omitempty"`
+	TurnDeadline string        `json:"turnDeadline,omitempty"`
+}
+
+// cleanMessage announces the table being cleaned.
+type cleanMessage struct {
+	Clean bool       `json:"clean"`
+	Turns []turnView `json:"turns"`
+}
+
+// votingMessage opens the mode vote (with the battle settings) or updates
+// its counts.
+type votingMessage struct {
+	Voting      map[string]int `json:"voting"`
+	Seed        *int64         `json:"seed,omitempty"`
+	Rules       string         `json:"rules,omitempty"`
+	TurnTimeout *int           `json:"turnTimeout,omitempty"`
+}
+
+// battleMessage opens the turns of a battle, after the vote if there was one.
+type battleMessage struct {
+	Battle       string         `json:"battle"`
+	Turns        []turnView     `json:"turns"`
+	Voting       map[string]int `json:"voting,omitempty"`
+	Tyrants      []tyrantView   `json:"tyrants"`
+	Seed         int64          `json:"seed"`
+	Rules        string         `json:"rules"`
+	Mode         string         `json:"mode,omitempty"`
+	TurnTimeout  int            `json:"turnTimeout"`
+	TurnDeadline string         `json:"turnDeadline,omitempty"`
+}
+
+// updateMessage carries the result of a turn: the new state (or the battle
+// outcome) and the queue.
+type updateMessage struct {
+	UpdateState stateUpdate `json:"updateState"`
+	Turns       []turnView  `json:"turns"`
+	// TurnSkipped or TurnExpired name the actor that lost its turn
+	TurnSkipped  string `json:"turnSkipped,omitempty"`
+	TurnExpired  string `json:"turnExpired,omitempty"`
+	TurnDeadline string `json:"turnDeadline,omitempty"`
+}
+
+// evolvedMessage announces a combatant swapped for its evolution.
+type evolvedMessage struct {
+	Evolved      evolvedInfo `json:"evolved"`
+	UpdateState  stateUpdate `json:"updateState"`
+	Turns        []turnView  `json:"turns"`
+	TurnDeadline string      `json:"turnDeadline,omitempty"`
+}
+
+type evolvedInfo struct {
+	User   string `json:"user"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Tyrant string `json:"tyrant"`
+	Name   string `json:"name"`
+	Asset  string `json:"asset"`
+}
+
+func (connectedMessage) messageType() string { return "connected" }
+func (errorMessage) messageType() string     { return "error" }
+func (ackMessage) messageType() string       { return "ack" }
+func (sessionMessage) messageType() string   { return "session" }
+func (resumedMessage) messageType() string   { return "resumed" }
+func (syncMessage) messageType() string      { return "sync" }
+func (imageMessage) messageType() string     { return "image" }
+func (joinedMessage) messageType() string    { return "joined" }
+func (leftMessage) messageType() string      { return "left" }
+func (cleanMessage) messageType() string     { return "clean" }
+func (votingMessage) messageType() string    { return "voting" }
+func (battleMessage) messageType() string    { return "battle" }
+func (updateMessage) messageType() string    { return "update" }
+func (evolvedMessage) messageType() string   { return "evolved" }
@@ -0,0 +1,62 @@
+package scene
+
+import (
+	"log"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Battle modes decided by the allies' vote. Ties go to TO_PARTY.
+const (
+	// ModeToParty ends the battle in DEFEAT as soon as any ally faints.
+	ModeToParty = "TO_PARTY"
+	// ModeUntilDeath fights until one side is down; a fainted ally is lost
+	// for good and released from its owner.
+	ModeUntilDeath = "UNTIL_DEATH"
+)
+
+// voteResultLocked closes the voting and fixes the battle mode.
+func (h *Hub) voteResultLocked() string {
+	h.votingActive = false
+	h.inBattle = true
+	h.mode = ModeToParty
+	if h.voteUntilDeath > h.voteToParty {
+		h.mode = ModeUntilDeath
+	}
+	h.armTurnTimerLocked()
+	return h.mode
+}
+
+// faintedLocked applies the mode consequences of a participant going down.
+func (h *Hub) faintedLocked(p *Participant) {
+	if p.Enemy || h.mode != ModeUntilDeath || !h.inBattle {
+		return
+	}
+	h.logEventLocked("death", map[string]any{"id": p.ID, "tyrant": p.Tyrant.ID, "user": p.Owner})
+	if p.Owner == "" {
+		return
+	}
+	owner, released := p.Owner, ""
+	h.userOps = append(h.userOps, func() error {
+		_, err := h.svc.UpdateUser(owner, models.UserUpdate{TyrantID: &released})
+		return err
+	})
+}
+
+// lostLocked reports whether an ally fainted for good in an UNTIL_DEATH battle.
+func (h *Hub) lostLocked(p *Participant) bool {
+	return h.mode == ModeUntilDeath && !p.Enemy && !p.Alive
+}
+
+// flushUserOps writes pending changes to the owners of the participants.
+func (h *Hub) flushUserOps() {
+	h.mu.Lock()
+	ops := h.userOps
+	h.userOps = nil
+	h.mu.Unlock()
+	for _, op := range ops {
+		if err := op(); err != nil {
+			log.Printf("scene: update owner %s: %v", h.id, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package scene
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Move categories, declared through attack attributes:
+//
+//	"heal" or "heal:percent"        restores a share of the target's max HP
+//	"buff:stat" or "buff:stat:n"    raises (or, with n < 0, lowers) attack or defense stages
+//	"revive" or "revive:percent"    brings a fainted target back with a share of its max HP
+//
+// Attacks without one of them deal damage.
+const (
+	MoveDamage = "damage"
+	MoveHeal   = "heal"
+	MoveBuff   = "buff"
+	MoveRevive = "revive"
+)
+
+// Move targets, declared through a "target:scope" attribute. Without one,
+// damage moves hit the other side and the rest target the user's side.
+const (
+	TargetSelf  = "self"
+	TargetAlly  = "ally" // the user or anyone on its side
+	TargetEnemy = "enemy"
+	TargetAny   = "any"
+)
+
+// Move areas, declared through "area:all", "area:random:n" or the "aoe"
+// shorthand for "area:all". Area moves hit every legal target (or n of them
+// picked at random) instead of a single one.
+const (
+	AreaSingle = "single"
+	AreaAll    = "all"
+	AreaRandom = "random"
+)
+
+// Stats that buff moves can change.
+const (
+	statAttack  = "attack"
+	statDefense = "defense"
+)
+
+const (
+	defaultHealPercent   = 50
+	defaultRevivePercent = 50
+	maxBuffStage         = 3
+	// spreadFactor scales the damage of a move that hits more than one target
+	spreadFactor = 0.75
+)
+
+// moveDef is how an attack behaves, read from its attributes.
+type moveDef struct {
+	category string
+	target   string
+	area     string
+	count    int    // targets picked by AreaRandom
+	percent  int    // heal and revive share of max HP
+	stat     string // buff stat
+	stages   int    // buff stages
+}
+
+// parseMove reads the category and targeting of an attack. Malformed
+// category attributes are ignored, leaving the attack a damage move.
+func parseMove(atk *models.Attack) moveDef {
+	m := moveDef{category: MoveDamage, area: AreaSingle}
+	for _, attr := range atk.Attributes {
+		parts := strings.Split(attr, ":")
+		switch parts[0] {
+		case "target":
+			if len(parts) == 2 && validTargetScope(parts[1]) {
+				m.target = parts[1]
+			}
+		case "aoe":
+			if len(parts) == 1 {
+				m.area = AreaAll
+			}
+		case "area":
+			switch {
+			case len(parts) == 2 && parts[1] == AreaAll:
+				m.area = AreaAll
+			case len(parts) == 3 && parts[1] == AreaRandom:
+				n, err := strconv.Atoi(parts[2])
+				if err == nil && n > 0 {
+					m.area, m.count = AreaRandom, n
+				}
+			}
+		case MoveHeal, MoveRevive:
+			if m.category != MoveDamage || len(parts) > 2 {
+				continue
+			}
+			percent := defaultHealPercent
+			if parts[0] == MoveRevive {
+				percent = defaultRevivePercent
+			}
+			if len(parts) == 2 {
+				v, err := strconv.Atoi(parts[1])
+				if err != nil || v <= 0 {
+					continue
+				}
+				percent = v
+			}
+			m.category, m.percent = parts[0], percent
+		case MoveBuff:
+			if m.category != MoveDamage || len(parts) < 2 || len(parts) > 3 {
+				continue
+			}
+			if parts[1] != statAttack && parts[1] != statDefense {
+				continue
+			}
+			stages := 1
+			if len(parts) == 3 {
+				v, err := strconv.Atoi(parts[2])
+				if err != nil || v == 0 {
+					continue
+				}
+				stages = v
+			}
+			m.category, m.stat, m.stages = MoveBuff, parts[1], stages
+		}
+	}
+	if m.target == "" {
+		m.target = TargetAlly
+		if m.category == MoveDamage {
+			m.target = TargetEnemy
+		}
+	}
+	return m
+}
+
+func validTargetScope(s string) bool {
+	switch s {
+	case TargetSelf, TargetAlly, TargetEnemy, TargetAny:
+		return true
+	}
+	return false
+}
+
+// legalTarget reports whether the move may be used by userID on targetID.
+// Revive moves need a fainted target; every other move needs it alive.
+func (m moveDef) legalTarget(userID, targetID string, user, target *Participant) bool {
+	if (m.category == MoveRevive) == target.Alive {
+		return false
+	}
+	switch m.target {
+	case TargetSelf:
+		return userID == targetID
+	case TargetAlly:
+		return user.Enemy == target.Enemy
+	case TargetEnemy:
+		return user.Enemy != target.Enemy
+	}
+	return true
+}
+
+// resolveTargetsLocked picks who an attack hits. Single target moves need
+// exactly one requested target. Area moves hit every requested target, or
+// every legal one when none is requested; AreaRandom then keeps count of them
+// at random. It returns the hit ids in a stable order, or an error message.
+func (h *Hub) resolveTargetsLocked(userID string, requested []string, m moveDef) ([]string, string) {
+	user := h.participants[userID]
+	legal := func(id string) bool {
+		p := h.participants[id]
+		return p != nil && m.legalTarget(userID, id, user, p) && !(m.category == MoveRevive && h.lostLocked(p))
+	}
+	if m.area == AreaSingle {
+		if len(requested) != 1 {
+			return nil, "attack takes a single target"
+		}
+		if h.participants[requested[0]] == nil {
+			return nil, "target not found"
+		}
+		if !legal(requested[0]) {
+			return nil, "invalid target for attack"
+		}
+		return requested, ""
+	}
+	var pool []string
+	if len(requested) == 0 {
+		for id := range h.participants {
+			if legal(id) {
+				pool = append(pool, id)
+			}
+		}
+	} else {
+		seen := make(map[string]bool, len(requested))
+		for _, id := range requested {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if h.participants[id] == nil {
+				return nil, "target not found"
+			}
+			if !legal(id) {
+				return nil, "invalid target for attack"
+			}
+			pool = append(pool, id)
+		}
+	}
+	if len(pool) == 0 {
+		return nil, "no valid targets"
+	}
+	sort.Strings(pool)
+	if m.area == AreaRandom && m.count < len(pool) {
+		// partial shuffle with the battle rng so replays pick the same targets
+		for i := 0; i < m.count; i++ {
+			j := i + h.rng.Intn(len(pool)-i)
+			pool[i], pool[j] = pool[j], pool[i]
+		}
+		pool = pool[:m.count]
+		sort.Strings(pool)
+	}
+	return pool, ""
+}
+
+// stageFactor converts buff stages to a stat multiplier: +50% per stage up,
+// and the inverse going down.
+func stageFactor(stages int) float64 {
+	if stages >= 0 {
+		return float64(2+stages) / 2
+	}
+	return 2 / float64(2-stages)
+}
+
+// buffModifiers are the stat stage multipliers of a hit, shown in the damage
+// breakdown like any other modifier.
+func buffModifiers(attacker, defender *Participant) []DamageModifier {
+	var mods []DamageModifier
+	if s := attacker.Buffs[statAttack]; s != 0 {
+		mods = append(mods, DamageModifier{Name: "attackStage", Factor: stageFactor(s)})
+	}
+	if s := defender.Buffs[statDefense]; s != 0 {
+		mods = append(mods, DamageModifier{Name: "defenseStage", Factor: 1 / stageFactor(s)})
+	}
+	return mods
+}
+
+// buffView lists the stat stages of a participant for snapshots.
+func (p *Participant) buffView() map[string]int {
+	out := make(map[string]int, len(p.Buffs))
+	for stat, stages := range p.Buffs {
+		out[stat] = stages
+	}
+	return out
+}
+
+// applySupportLocked resolves a heal, buff or revive move on the target and
+// adds its effect to lastAttack and the log data.
+func (h *Hub) applySupportLocked(m moveDef, target *Participant, lastAttack *attackResult, logData map[string]any) {
+	lastAttack.Category = m.category
+	logData["category"] = m.category
+	switch m.category {
+	case MoveHeal:
+		healed := target.FullHP * m.percent / 100
+		if healed < 1 {
+			healed = 1
+		}
+		if target.CurrentHP+healed > target.FullHP {
+			healed = target.FullHP - target.CurrentHP
+		}
+		target.CurrentHP += healed
+		lastAttack.Healed = &healed
+		logData["healed"] = healed
+	case MoveRevive:
+		hp := target.FullHP * m.percent / 100
+		if hp < 1 {
+			hp = 1
+		}
+		if hp > target.FullHP {
+			hp = target.FullHP
+		}
+		target.Alive = true
+		target.CurrentHP = hp
+		lastAttack.Revived = hp
+		logData["revived"] = hp
+	case MoveBuff:
+		if target.Buffs == nil {
+			target.Buffs = make(map[string]int)
+		}
+		before := target.Buffs[m.stat]
+		after := before + m.stages
+		if after > maxBuffStage {
+			after = maxBuffStage
+		}
+		if after < -maxBuffStage {
+			after = -maxBuffStage
+		}
+		if after == 0 {
+			delete(target.Buffs, m.stat)
+		} else {
+			target.Buffs[m.stat] = after
+		}
+		buff := buffResult{Stat: m.stat, Change: after - before, Stages: after}
+		lastAttack.Buff = &buff
+		logData["buff"] = buff
+	}
+}
@@ -0,0 +1,174 @@
+package scene
+
+// GM overrides, sent through "override", for rulings the battle rules cannot
+// express.
+const (
+	// OverrideHP sets the current HP of an alive combatant.
+	OverrideHP = "hp"
+	// OverrideRevive brings a fainted combatant back, with full HP by default.
+	OverrideRevive = "revive"
+	// OverrideKnockOut drops a combatant to 0 HP, with the usual fainting
+	// consequences.
+	OverrideKnockOut = "knockOut"
+	// OverridePP sets the PP left of one attack.
+	OverridePP = "pp"
+	// OverrideTurn hands the turn to a combatant right away.
+	OverrideTurn = "turn"
+	// OverrideOrder replaces the turn order until the next join or leave.
+	OverrideOrder = "order"
+)
+
+// overrideCommand is a GM correction of the battle state.
+type overrideCommand struct {
+	Type   string `json:"type"`
+	Target string `json:"target,omitempty"`
+	// Value is the HP or PP to set
+	Value  *int   `json:"value,omitempty"`
+	Attack string `json:"attack,omitempty"`
+	// Order lists every combatant id once, in the new turn order
+	Order []string `json:"order,omitempty"`
+}
+
+// handleOverride applies a GM override and broadcasts the new state. The
+// battle ends when it leaves one side down, and the turn moves on when the
+// current actor can no longer act.
+func (h *Hub) handleOverride(cmd overrideCommand) *errorMessage {
+	fail := func(msg string) *errorMessage {
+		h.mu.Unlock()
+		return &errorMessage{Error: msg}
+	}
+	h.mu.Lock()
+	p := h.participants[cmd.Target]
+	if p == nil && cmd.Type != OverrideOrder {
+		return fail("target not found")
+	}
+	lastOverride := overrideResult{Type: cmd.Type, Target: cmd.Target}
+	switch cmd.Type {
+	case OverrideHP:
+		if !p.Alive {
+			return fail("target fainted")
+		}
+		if cmd.Value == nil || *cmd.Value < 1 || *cmd.Value > p.FullHP {
+			return fail("invalid hp")
+		}
+		p.CurrentHP = *cmd.Value
+		lastOverride.HP = cmd.Value
+	case OverrideRevive:
+		if p.Alive {
+			return fail("target not fainted")
+		}
+		if h.lostLocked(p) {
+			return fail("target lost")
+		}
+		hp := p.FullHP
+		if cmd.Value != nil {
+			hp = *cmd.Value
+		}
+		if hp < 1 || hp > p.FullHP {
+			return fail("invalid hp")
+		}
+		p.Alive = true
+		p.CurrentHP = hp
+		lastOverride.HP = &hp
+	case OverrideKnockOut:
+		if !p.Alive {
+			return fail("target fainted")
+		}
+		h.damageLocked(p, p.CurrentHP)
+		hp := 0
+		lastOverride.HP = &hp
+	case OverridePP:
+		pp := p.AttackPP[cmd.Attack]
+		if pp == nil {
+			return fail("unknown attack")
+		}
+		if cmd.Value == nil || *cmd.Value < 0 || *cmd.Value > pp.Full {
+			return fail("invalid pp")
+		}
+		pp.Current = *cmd.Value
+		lastOverride.Attack = cmd.Attack
+		lastOverride.PP = cmd.Value
+	case OverrideTurn:
+		if !h.inBattle && !h.votingActive {
+			return fail("not in battle")
+		}
+		if !p.Alive {
+			return fail("target fainted")
+		}
+		h.currentActor = cmd.Target
+		h.alignTurnIndexLocked()
+		h.armTurnTimerLocked()
+	case OverrideOrder:
+		if !h.inBattle && !h.votingActive {
+			return fail("not in battle")
+		}
+		if !h.validOrderLocked(cmd.Order) {
+			return fail("invalid order")
+		}
+		h.turnOrder = append([]string(nil), cmd.Order...)
+		h.alignTurnIndexLocked()
+		lastOverride.Order = cmd.Order
+	default:
+		return fail("unknown override")
+	}
+	h.logEventLocked("override", lastOverride)
+	var status *stateUpdate
+	var events []statusEvent
+	if h.inBattle {
+		status, events = h.settleLocked()
+	}
+	if status == nil {
+		state := h.stateLocked(events)
+		state.LastOverride = &lastOverride
+		status = &state
+	}
+	payload := h.updateLocked(*status)
+	h.mu.Unlock()
+
+	h.broadcast(payload)
+	return nil
+}
+
+// validOrderLocked reports whether order lists every combatant exactly once.
+func (h *Hub) validOrderLocked(order []string) bool {
+	if len(order) != len(h.participants) {
+		return false
+	}
+	seen := make(map[string]bool, len(order))
+	for _, id := range order {
+		if h.participants[id] == nil || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// alignTurnIndexLocked points turnIndex right after the current actor, so the
+// queue goes on from it.
+func (h *Hub) alignTurnIndexLocked() {
+	h.turnIndex = 0
+	for i, id := range h.turnOrder {
+		if id == h.currentActor {
+			h.turnIndex = (i + 1) % len(h.turnOrder)
+			return
+		}
+	}
+}
+
+// settleLocked checks the battle after an override: it finishes the battle
+// when a side is down, or passes the turn on when the current actor fainted.
+// The final updateState value is returned once the battle ends.
+func (h *Hub) settleLocked() (*stateUpdate, []statusEvent) {
+	var events []statusEvent
+	if actor := h.participants[h.currentActor]; actor == nil || !actor.Alive {
+		if h.outcomeLocked() == "" {
+			events = h.advanceTurnLocked()
+		}
+	}
+	if outcome := h.outcomeLocked(); outcome != "" {
+		result := h.finishBattleLocked(outcome)
+		return &result, events
+	}
+	return nil, events
+}
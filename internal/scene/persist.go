@@ -0,0 +1,201 @@
+package scene
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/matheustorresii/tyrants-back/internal/db"
+)
+
+// checkpointState is the persisted form of a hub's battle state. Socket
+// bindings are not stored: clients re-bind by sending resume with their
+// session token, or join again.
+type checkpointState struct {
+	Participants      map[string]*Participant `json:"participants"`
+	TurnOrder         []string                `json:"turnOrder"`
+	TurnIndex         int                     `json:"turnIndex"`
+	InBattle          bool                    `json:"inBattle"`
+	CurrentActor      string                  `json:"currentActor"`
+	BattleStartedWith string                  `json:"battleStartedWith"`
+	VotingActive      bool                    `json:"votingActive"`
+	VoteUntilDeath    int                     `json:"voteUntilDeath"`
+	VoteToParty       int                     `json:"voteToParty"`
+	VotedAllies       map[string]string       `json:"votedAllies"`
+	TotalAllies       int                     `json:"totalAllies"`
+	BattleID          string                  `json:"battleId"`
+	EventSeq          int                     `json:"eventSeq"`
+	Seed              int64                   `json:"seed"`
+	RandCalls         []int                   `json:"randCalls"`
+	Rules             string                  `json:"rules"`
+	Mode              string                  `json:"mode"`
+	TurnTimeout       int                     `json:"turnTimeout"`
+	AIDelay           int                     `json:"aiDelay"`
+	Sessions          map[string]string       `json:"sessions"`
+	// Image and Fill are only read, from checkpoints written before the
+	// image was stored on its own
+	Image string `json:"image,omitempty"`
+	Fill  *bool  `json:"fill,omitempty"`
+}
+
+// sceneImage is the stored form of the room image, saved apart from the
+// checkpoint because it may be a data URL of several MiB.
+type sceneImage struct {
+	Image string `json:"image"`
+	Fill  *bool  `json:"fill,omitempty"`
+}
+
+func (h *Hub) checkpointLocked() checkpointState {
+	return checkpointState{
+		Participants:      h.participants,
+		TurnOrder:         h.turnOrder,
+		TurnIndex:         h.turnIndex,
+		InBattle:          h.inBattle,
+		CurrentActor:      h.currentActor,
+		BattleStartedWith: h.battleStartedWith,
+		VotingActive:      h.votingActive,
+		VoteUntilDeath:    h.voteUntilDeath,
+		VoteToParty:       h.voteToParty,
+		VotedAllies:       h.votedAllies,
+		TotalAllies:       h.totalAllies,
+		BattleID:          h.battleID,
+		EventSeq:          h.eventSeq,
+		Seed:              h.rng.seed,
+		RandCalls:         h.rng.calls,
+		Rules:             h.rules,
+		Mode:              h.mode,
+		TurnTimeout:       int(h.turnTimeout / time.Second),
+		AIDelay:           int(h.aiDelay / time.Millisecond),
+		Sessions:          h.sessions,
+	}
+}
+
+// checkpoint persists the current battle state of the room, and the image
+// when it changed since it was last saved. An empty room, with no
+// participants, battle or image, drops its checkpoint so it is not
+// resurrected on the next start.
+func (h *Hub) checkpoint() {
+	h.mu.Lock()
+	st := h.checkpointLocked()
+	empty := len(st.Participants) == 0 && !st.InBattle && !st.VotingActive && h.image == ""
+	data, err := json.Marshal(st)
+	h.saveSeq++
+	seq := h.saveSeq
+	img, imageSeq := sceneImage{Image: h.image, Fill: h.fill}, h.imageSeq
+	h.mu.Unlock()
+	if err != nil {
+		log.Printf("scene: encode checkpoint %s: %v", h.id, err)
+		return
+	}
+
+	// serialize writes and never let an older snapshot overwrite a newer one
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	if seq <= h.savedSeq {
+		return
+	}
+	h.savedSeq = seq
+	if empty {
+		if err = h.svc.DeleteSceneState(h.id); err == nil {
+			// the image went with the row
+			h.savedImageSeq = imageSeq
+		}
+	} else if err = h.svc.SaveSceneState(h.id, data); err == nil && imageSeq > h.savedImageSeq {
+		var image []byte
+		if img.Image != "" {
+			image, _ = json.Marshal(img)
+		}
+		if err = h.svc.SaveSceneImage(h.id, image); err == nil {
+			h.savedImageSeq = imageSeq
+		}
+	}
+	if err != nil {
+		log.Printf("scene: save checkpoint %s: %v", h.id, err)
+	}
+}
+
+// restore loads a checkpoint and its stored image, if any, into a freshly
+// created hub.
+func (h *Hub) restore(data, image []byte) error {
+	var st checkpointState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	img := sceneImage{Image: st.Image, Fill: st.Fill}
+	if image != nil {
+		if err := json.Unmarshal(image, &img); err != nil {
+			return err
+		}
+	}
+	var chart typeChart
+	if st.InBattle || st.VotingActive {
+		entries, err := h.svc.ListTypeChart()
+		if err != nil {
+			log.Printf("scene: load type chart: %v", err)
+		}
+		chart = newTypeChart(entries)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if st.Participants != nil {
+		h.participants = st.Participants
+		for id, p := range h.participants {
+			// checkpoints from before combatant ids were keyed by species
+			if p.ID == "" {
+				p.ID = id
+			}
+		}
+	}
+	h.turnOrder = st.TurnOrder
+	h.turnIndex = st.TurnIndex
+	h.inBattle = st.InBattle
+	h.currentActor = st.CurrentActor
+	h.battleStartedWith = st.BattleStartedWith
+	h.votingActive = st.VotingActive
+	h.voteUntilDeath = st.VoteUntilDeath
+	h.voteToParty = st.VoteToParty
+	h.votedAllies = st.VotedAllies
+	h.totalAllies = st.TotalAllies
+	h.battleID = st.BattleID
+	h.eventSeq = st.EventSeq
+	h.rng = resumeBattleRand(h.newRand, st.Seed, st.RandCalls)
+	if calc, ok := damageCalculator(st.Rules); ok {
+		h.rules, h.damage = st.Rules, calc
+	}
+	h.mode = st.Mode
+	if st.Sessions != nil {
+		h.sessions = st.Sessions
+	}
+	h.image, h.fill = img.Image, img.Fill
+	if image == nil && h.image != "" {
+		// moved out of an older checkpoint on the next save
+		h.imageSeq++
+	}
+	h.typeChart = chart
+	h.turnTimeout = time.Duration(st.TurnTimeout) * time.Second
+	if st.AIDelay > 0 {
+		h.aiDelay = time.Duration(st.AIDelay) * time.Millisecond
+	}
+	// the turn in progress gets a fresh countdown
+	h.armTurnTimerLocked()
+	return nil
+}
+
+// loadCheckpoint restores the room from its stored checkpoint, if any.
+func (h *Hub) loadCheckpoint() {
+	data, err := h.svc.LoadSceneState(h.id)
+	if err != nil {
+		if !errors.Is(err, db.ErrSceneStateNotFound) {
+			log.Printf("scene: load checkpoint %s: %v", h.id, err)
+		}
+		return
+	}
+	image, err := h.svc.LoadSceneImage(h.id)
+	if err != nil && !errors.Is(err, db.ErrSceneStateNotFound) {
+		log.Printf("scene: load image %s: %v", h.id, err)
+	}
+	if err := h.restore(data, image); err != nil {
+		log.Printf("scene: restore checkpoint %s: %v", h.id, err)
+	}
+}
@@ -0,0 +1,29 @@
+package scene
+
+import "testing"
+
+func TestCheckpointSavesImageOnlyWhenChanged(t *testing.T) {
+	svc := &stubService{tyrants: replayRoster()}
+	h := NewHub(svc, NewMathRand)
+	gm := &Client{role: RoleGM, send: make(chan []byte, sendQueueSize), done: make(chan struct{})}
+
+	if failure := h.dispatch(gm, command{Payload: &imagePayload{Image: "data:image/png;base64,AAAA"}}); failure != nil {
+		t.Fatalf("image: %s", failure.Error)
+	}
+	if failure := h.dispatch(gm, command{Payload: &joinRequest{TyrantID: "tumba"}}); failure != nil {
+		t.Fatalf("join: %s", failure.Error)
+	}
+	if failure := h.dispatch(gm, command{Payload: &attackEvent{User: "tumba", Target: "tumba", Attack: "bite"}}); failure == nil {
+		t.Fatal("attack outside a battle was not refused")
+	}
+	if len(svc.images) != 1 {
+		t.Fatalf("image saved %d times, want once", len(svc.images))
+	}
+
+	if failure := h.dispatch(gm, command{Payload: &imagePayload{}}); failure != nil {
+		t.Fatalf("clear image: %s", failure.Error)
+	}
+	if len(svc.images) != 2 || svc.images[1] != nil {
+		t.Fatalf("images saved = %q, want the image then a clear", svc.images)
+	}
+}
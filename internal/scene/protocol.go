@@ -0,0 +1,299 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ProtocolVersion is the newest scene protocol the server speaks. Clients pick
+// theirs with ?v=N when connecting: 0 (or no v) is the legacy flat format, 1
+// wraps every message in an envelope. Newer clients are negotiated down.
+const ProtocolVersion = 1
+
+// LegacyProtocol is the flat format older app builds speak.
+const LegacyProtocol = 0
+
+// Replies to envelopes the server cannot handle.
+var (
+	errInvalidMessage     = errors.New("invalid message")
+	errUnsupportedVersion = errors.New("unsupported version")
+	errUnknownType        = errors.New("unknown message type")
+	errInvalidPayload     = errors.New("invalid payload")
+)
+
+// negotiateVersion reads the protocol version requested on connect. ok is
+// false when v is not a version number.
+func negotiateVersion(r *http.Request) (version int, ok bool) {
+	raw := r.URL.Query().Get("v")
+	if raw == "" {
+		return LegacyProtocol, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	if v > ProtocolVersion {
+		v = ProtocolVersion
+	}
+	return v, true
+}
+
+// envelope is a client message in protocol v1 and later.
+type envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// outgoingEnvelope is a server message in protocol v1 and later.
+type outgoingEnvelope struct {
+	V       int           `json:"v"`
+	Type    string        `json:"type"`
+	Payload serverMessage `json:"payload"`
+}
+
+// Client message payloads not shared with the legacy handlers.
+type imagePayload struct {
+	Image string `json:"image"`
+	Fill  *bool  `json:"fill,omitempty"`
+}
+
+type battlePayload struct {
+	StartWith   string `json:"startWith"`
+	VoteEnabled bool   `json:"voteEnabled,omitempty"`
+	Seed        *int64 `json:"seed,omitempty"`
+	Rules       string `json:"rules,omitempty"`
+	TurnTimeout int    `json:"turnTimeout,omitempty"`
+	AIDelay     int    `json:"aiDelay,omitempty"`
+}
+
+type cleanPayload struct {
+	IncludeAllies bool `json:"includeAllies,omitempty"`
+}
+
+// leavePayload names the ally leaving; empty means the sender's own.
+type leavePayload struct {
+	Tyrant string `json:"tyrant,omitempty"`
+}
+
+// votePayload is a mode vote; an empty user votes for the sender's own ally.
+type votePayload struct {
+	User   string `json:"user,omitempty"`
+	Choice string `json:"choice"`
+}
+
+type resumePayload struct {
+	Token string `json:"token"`
+}
+
+// syncPayload asks for the full state of the room; it has no fields.
+type syncPayload struct{}
+
+// setup fills the battle defaults: a fresh seed and the classic rules.
+func (b battlePayload) setup() battleSetup {
+	s := battleSetup{
+		StartWith:   b.StartWith,
+		VoteEnabled: b.VoteEnabled,
+		Seed:        timeSeed(),
+		Rules:       RulesClassic,
+		TurnTimeout: b.TurnTimeout,
+		AIDelay:     b.AIDelay,
+	}
+	if b.Seed != nil {
+		s.Seed = *b.Seed
+	}
+	if b.Rules != "" {
+		s.Rules = b.Rules
+	}
+	return s
+}
+
+// clientPayloads builds the payload of each client message type.
+var clientPayloads = map[string]func() any{
+	"image":  func() any { return &imagePayload{} },
+	"join":   func() any { return &joinRequest{} },
+	"battle": func() any { return &battlePayload{} },
+	"attack": func() any { return &attackEvent{} },
+	"item":   func() any { return &itemEvent{} },
+	"action": func() any { return &actionEvent{} },
+	"status": func() any { return &statusCommand{} },
+	"clean":  func() any { return &cleanPayload{} },
+	"leave":  func() any { return &leavePayload{} },
+	"vote":   func() any { return &votePayload{} },
+	"resume": func() any { return &resumePayload{} },
+	"sync":   func() any { return &syncPayload{} },
+
+	// GM corrections of the battle state
+	"override": func() any { return &overrideCommand{} },
+}
+
+// command is a decoded client message, whatever the protocol version.
+type command struct {
+	// ID is the client's request id; empty for legacy messages
+	ID      string
+	Payload any
+}
+
+// decodeStrict decodes data into v, refusing unknown fields and trailing data.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+// decodeEnvelope decodes a message of a versioned client. The error is the
+// reply to send back.
+func decodeEnvelope(data []byte, version int) (command, error) {
+	var env envelope
+	if err := decodeStrict(data, &env); err != nil {
+		return command{}, errInvalidMessage
+	}
+	if env.V != version {
+		return command{ID: env.ID}, errUnsupportedVersion
+	}
+	newPayload, ok := clientPayloads[env.Type]
+	if !ok {
+		return command{ID: env.ID}, errUnknownType
+	}
+	payload := newPayload()
+	if len(env.Payload) > 0 && string(env.Payload) != "null" {
+		if err := decodeStrict(env.Payload, payload); err != nil {
+			return command{ID: env.ID}, errInvalidPayload
+		}
+	}
+	return command{ID: env.ID, Payload: payload}, nil
+}
+
+// incoming is a legacy message: its intent is the first field that is set.
+type incoming struct {
+	Image         *string        `json:"image,omitempty"`
+	Fill          *bool          `json:"fill,omitempty"`
+	Battle        *string        `json:"battle,omitempty"`
+	VoteEnabled   *bool          `json:"voteEnabled,omitempty"`
+	Seed          *int64         `json:"seed,omitempty"`
+	Rules         *string        `json:"rules,omitempty"`
+	TurnTimeout   *int           `json:"turnTimeout,omitempty"`
+	AIDelay       *int           `json:"aiDelay,omitempty"`
+	AI            *string        `json:"ai,omitempty"`
+	Join          *string        `json:"join,omitempty"`
+	Instance      *string        `json:"instance,omitempty"`
+	Name          *string        `json:"name,omitempty"`
+	Enemy         *bool          `json:"enemy,omitempty"`
+	Level         *int           `json:"level,omitempty"`
+	Attack        *attackEvent   `json:"attack,omitempty"`
+	Item          *itemEvent     `json:"item,omitempty"`
+	Action        *actionEvent   `json:"action,omitempty"`
+	Clean         *bool          `json:"clean,omitempty"`
+	IncludeAllies *bool          `json:"includeAllies,omitempty"`
+	Leave         *string        `json:"leave,omitempty"`
+	Resume        *string        `json:"resume,omitempty"`
+	Sync          *bool          `json:"sync,omitempty"`
+	Vote          *string        `json:"vote,omitempty"`
+	User          *string        `json:"user,omitempty"`
+	Status        *statusCommand `json:"status,omitempty"`
+	// Override is the GM correction; see overrideCommand
+	Override *overrideCommand `json:"override,omitempty"`
+	// ID is the optional request id the reply refers to
+	ID string `json:"id,omitempty"`
+}
+
+// decodeLegacy turns a legacy message into the command it stands for. ok is
+// false for messages that are not valid JSON or carry nothing the scene
+// knows; legacy clients never got a reply for those.
+func decodeLegacy(data []byte) (cmd command, ok bool) {
+	var msg incoming
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("scene: invalid message: %v", err)
+		return command{}, false
+	}
+	cmd, ok = legacyCommand(msg)
+	cmd.ID = msg.ID
+	return cmd, ok
+}
+
+// legacyCommand picks the command of a legacy message by its first set field.
+func legacyCommand(msg incoming) (command, bool) {
+	switch {
+	case msg.Image != nil:
+		p := &imagePayload{Image: *msg.Image, Fill: msg.Fill}
+		return command{Payload: p}, true
+	case msg.Join != nil:
+		return command{Payload: &joinRequest{
+			TyrantID: *msg.Join,
+			Instance: msg.Instance,
+			Name:     msg.Name,
+			Enemy:    msg.Enemy,
+			Level:    msg.Level,
+			User:     msg.User,
+			AI:       msg.AI,
+		}}, true
+	case msg.Battle != nil:
+		p := &battlePayload{StartWith: *msg.Battle, Seed: msg.Seed}
+		if msg.VoteEnabled != nil {
+			p.VoteEnabled = *msg.VoteEnabled
+		}
+		if msg.Rules != nil {
+			p.Rules = *msg.Rules
+		}
+		if msg.TurnTimeout != nil {
+			p.TurnTimeout = *msg.TurnTimeout
+		}
+		if msg.AIDelay != nil {
+			p.AIDelay = *msg.AIDelay
+		}
+		return command{Payload: p}, true
+	case msg.Attack != nil:
+		return command{Payload: msg.Attack}, true
+	case msg.Item != nil:
+		return command{Payload: msg.Item}, true
+	case msg.Action != nil:
+		return command{Payload: msg.Action}, true
+	case msg.Status != nil:
+		return command{Payload: msg.Status}, true
+	case msg.Override != nil:
+		return command{Payload: msg.Override}, true
+	case msg.Clean != nil && *msg.Clean:
+		p := &cleanPayload{}
+		if msg.IncludeAllies != nil {
+			p.IncludeAllies = *msg.IncludeAllies
+		}
+		return command{Payload: p}, true
+	case msg.Resume != nil:
+		return command{Payload: &resumePayload{Token: *msg.Resume}}, true
+	case msg.Sync != nil && *msg.Sync:
+		return command{Payload: &syncPayload{}}, true
+	case msg.Leave != nil:
+		p := &leavePayload{Tyrant: *msg.Leave}
+		if p.Tyrant == "" && msg.User != nil {
+			p.Tyrant = *msg.User
+		}
+		return command{Payload: p}, true
+	case msg.Vote != nil:
+		p := &votePayload{Choice: *msg.Vote}
+		if msg.User != nil {
+			p.User = *msg.User
+		}
+		return command{Payload: p}, true
+	}
+	return command{}, false
+}
+
+// encodeMessage encodes m for a client speaking version: flat for legacy
+// clients, wrapped in an envelope otherwise.
+func encodeMessage(m serverMessage, version int) ([]byte, error) {
+	if version == LegacyProtocol {
+		return json.Marshal(m)
+	}
+	return json.Marshal(outgoingEnvelope{V: version, Type: m.messageType(), Payload: m})
+}
@@ -0,0 +1,52 @@
+package scene
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Rand is the random source a battle draws every roll from.
+type Rand interface {
+	Intn(n int) int
+}
+
+// RandFactory builds the random source of a battle from its seed. The same
+// seed must always produce the same sequence so battles can be re-simulated.
+type RandFactory func(seed int64) Rand
+
+// NewMathRand is the default RandFactory, backed by math/rand.
+func NewMathRand(seed int64) Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// battleRand wraps the battle's source and records the argument of every draw,
+// so the exact position in the sequence can be rebuilt from the seed after a
+// restart.
+type battleRand struct {
+	src   Rand
+	seed  int64
+	calls []int
+}
+
+func newBattleRand(factory RandFactory, seed int64) *battleRand {
+	return &battleRand{src: factory(seed), seed: seed}
+}
+
+// resumeBattleRand rebuilds a source and replays previous draws to reach the
+// same position in the sequence.
+func resumeBattleRand(factory RandFactory, seed int64, calls []int) *battleRand {
+	r := newBattleRand(factory, seed)
+	for _, n := range calls {
+		r.Intn(n)
+	}
+	return r
+}
+
+func (r *battleRand) Intn(n int) int {
+	r.calls = append(r.calls, n)
+	return r.src.Intn(n)
+}
+
+func timeSeed() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,157 @@
+package scene
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// stubService serves a fixed roster and records the battle log in memory.
+type stubService struct {
+	tyrants  map[string]models.Tyrant
+	events   []models.BattleEvent
+	outcomes []string
+	// images are the scene images saved, in order
+	images [][]byte
+}
+
+func (s *stubService) GetUser(id string) (models.User, error) {
+	return models.User{ID: id}, nil
+}
+
+func (s *stubService) GetTyrant(id string) (models.Tyrant, error) {
+	t, ok := s.tyrants[id]
+	if !ok {
+		return models.Tyrant{}, errors.New("tyrant not found")
+	}
+	return t, nil
+}
+
+func (s *stubService) ListTypeChart() ([]models.TypeEffectiveness, error) { return nil, nil }
+func (s *stubService) SaveSceneState(string, []byte) error                { return nil }
+func (s *stubService) LoadSceneState(string) ([]byte, error)              { return nil, nil }
+func (s *stubService) LoadSceneImage(string) ([]byte, error)              { return nil, nil }
+func (s *stubService) ListSceneStates() (map[string][]byte, error)        { return nil, nil }
+func (s *stubService) DeleteSceneState(string) error                      { return nil }
+func (s *stubService) CreateBattle(models.Battle) error                   { return nil }
+
+func (s *stubService) SaveSceneImage(_ string, image []byte) error {
+	s.images = append(s.images, image)
+	return nil
+}
+
+func (s *stubService) AppendBattleEvent(_ string, e models.BattleEvent) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *stubService) FinishBattle(_, outcome, _ string) error {
+	s.outcomes = append(s.outcomes, outcome)
+	return nil
+}
+
+func (s *stubService) UpdateUser(id string, _ models.UserUpdate) (models.UserDetails, error) {
+	return models.UserDetails{}, nil
+}
+
+func (s *stubService) ConsumeUserItem(string, string) (int, error) { return 0, nil }
+func (s *stubService) AwardXP(map[string]int) error                { return nil }
+
+func replayRoster() map[string]models.Tyrant {
+	attacks := []models.Attack{
+		{Name: "bite", Power: 40, PP: 10, Accuracy: 90, Attributes: []string{"poison:30"}},
+		{Name: "slam", Power: 60, PP: 5, Accuracy: 75},
+	}
+	return map[string]models.Tyrant{
+		"tumba": {ID: "tumba", Types: []string{"normal"}, Attacks: attacks, HP: 600, Attack: 40, Defense: 30, Speed: 20},
+		"golem": {ID: "golem", Types: []string{"normal"}, Attacks: attacks, HP: 800, Attack: 35, Defense: 40, Speed: 20},
+	}
+}
+
+// playSeededBattle runs two allies against two enemies of the same speed,
+// every actor hitting the first foe alive with its first attack with PP left,
+// and returns the battle log and outcomes.
+func playSeededBattle(t *testing.T, seed int64) ([]models.BattleEvent, []string) {
+	t.Helper()
+	svc := &stubService{tyrants: replayRoster()}
+	h := NewHub(svc, NewMathRand)
+	c := &Client{send: make(chan []byte, sendQueueSize), done: make(chan struct{})}
+	enemy := true
+	for _, req := range []joinRequest{
+		{TyrantID: "golem", Enemy: &enemy},
+		{TyrantID: "tumba"},
+		{TyrantID: "golem"},
+		{TyrantID: "tumba", Enemy: &enemy},
+	} {
+		if failure := h.handleJoin(c, req); failure != nil {
+			t.Fatalf("join %s: %s", req.TyrantID, failure.Error)
+		}
+		// only the session token is queued for the client
+		<-c.send
+	}
+	if failure := h.handleBattle(battleSetup{StartWith: "golem", Seed: seed}); failure != nil {
+		t.Fatalf("battle: %s", failure.Error)
+	}
+	for turn := 0; ; turn++ {
+		if turn > 200 {
+			t.Fatal("battle did not end")
+		}
+		h.mu.RLock()
+		inBattle, actor := h.inBattle, h.currentActor
+		var ev attackEvent
+		if p := h.participants[actor]; p != nil {
+			ev.User = actor
+			for _, atk := range p.Tyrant.Attacks {
+				if p.AttackPP[atk.Name].Current > 0 {
+					ev.Attack = atk.Name
+					break
+				}
+			}
+			for _, id := range h.turnOrder {
+				if foe := h.participants[id]; foe.Alive && foe.Enemy != p.Enemy {
+					ev.Target = id
+					break
+				}
+			}
+		}
+		h.mu.RUnlock()
+		if !inBattle {
+			break
+		}
+		var failure *errorMessage
+		if ev.Attack == "" {
+			failure = h.handleAction(actionEvent{User: actor, Type: "struggle", Target: ev.Target})
+		} else {
+			failure = h.handleAttack(ev)
+		}
+		if failure != nil {
+			t.Fatalf("turn %d of %s: %s", turn, actor, failure.Error)
+		}
+		h.flushBattleLog()
+	}
+	h.flushBattleLog()
+	return svc.events, svc.outcomes
+}
+
+func TestSeededBattleReplays(t *testing.T) {
+	events, outcomes := playSeededBattle(t, 42)
+	again, againOutcomes := playSeededBattle(t, 42)
+
+	if len(outcomes) != 1 {
+		t.Fatalf("outcomes = %v, want one", outcomes)
+	}
+	if !reflect.DeepEqual(outcomes, againOutcomes) {
+		t.Errorf("outcomes differ: %v vs %v", outcomes, againOutcomes)
+	}
+	if len(events) != len(again) {
+		t.Fatalf("logged %d events, then %d", len(events), len(again))
+	}
+	for i := range events {
+		a, b := events[i], again[i]
+		if a.Seq != b.Seq || a.Type != b.Type || string(a.Data) != string(b.Data) {
+			t.Errorf("event %d differs:\n%s %s\n%s %s", i, a.Type, a.Data, b.Type, b.Data)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package scene
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/matheustorresii/tyrants-back/internal/auth"
+	"github.com/matheustorresii/tyrants-back/internal/db"
+)
+
+// Connection roles, decided on upgrade from the calling user.
+const (
+	// RoleGM is an admin user: it runs the scene and may act for any Tyrant.
+	RoleGM = "gm"
+	// RolePlayer is a known user: it joins allies and acts only for the
+	// Tyrants bound to its own connection.
+	RolePlayer = "player"
+	// RoleSpectator is an anonymous connection that only receives updates.
+	RoleSpectator = "spectator"
+)
+
+// authenticate resolves the role of a connecting request. Unknown users are
+// rejected with 401 rather than downgraded, so typos do not go unnoticed.
+// The user id is taken at face value, like everywhere else in the API, so
+// roles keep honest clients apart but do not stop impersonation.
+func (h *Hub) authenticate(r *http.Request) (role, userID string, status int) {
+	userID = auth.CallerID(r)
+	if userID == "" {
+		return RoleSpectator, "", 0
+	}
+	u, err := h.svc.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return "", "", http.StatusUnauthorized
+		}
+		return "", "", http.StatusInternalServerError
+	}
+	if u.Admin {
+		return RoleGM, u.ID, 0
+	}
+	return RolePlayer, u.ID, 0
+}
+
+// boundTyrant returns a Tyrant bound to the client, if any.
+func (h *Hub) boundTyrant(c *Client) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for id, cli := range h.tyrantIDToClient {
+		if cli == c {
+			return id
+		}
+	}
+	return ""
+}
+
+// mayActFor reports whether the client may act on behalf of the Tyrant:
+// the GM always may, players only through Tyrants bound to their connection.
+func (h *Hub) mayActFor(c *Client, tyrantID string) bool {
+	switch c.role {
+	case RoleGM:
+		return true
+	case RolePlayer:
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return tyrantID != "" && h.tyrantIDToClient[tyrantID] == c
+	}
+	return false
+}
+
+// mayJoin checks a join from a player: allies only, owned by the player
+// itself, at the default level, and not taking over a combatant another user
+// owns.
+func (h *Hub) mayJoin(c *Client, req joinRequest) bool {
+	switch c.role {
+	case RoleGM:
+		return true
+	case RoleSpectator:
+		return false
+	}
+	if req.Enemy != nil && *req.Enemy || req.AI != nil && *req.AI != "" {
+		return false
+	}
+	// the level scales damage under the leveled rules, so only the GM sets it
+	if req.Level != nil {
+		return false
+	}
+	if req.User != nil && *req.User != "" && *req.User != c.user {
+		return false
+	}
+	if req.Instance == nil || *req.Instance == "" {
+		return true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if p := h.participants[*req.Instance]; p != nil && (p.Enemy || p.Owner != "" && p.Owner != c.user) {
+		return false
+	}
+	return true
+}
+
+// mayResumeLocked checks that a session token is used by a player (or the
+// GM) that owns the Tyrant it resumes.
+func (h *Hub) mayResumeLocked(c *Client, tyrantID string) bool {
+	switch c.role {
+	case RoleGM:
+		return true
+	case RolePlayer:
+		p := h.participants[tyrantID]
+		return p != nil && (p.Owner == "" || p.Owner == c.user)
+	}
+	return false
+}
+
+func forbidden() *errorMessage {
+	return &errorMessage{Error: "forbidden"}
+}
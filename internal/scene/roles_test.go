@@ -0,0 +1,19 @@
+package scene
+
+import "testing"
+
+func TestOnlyTheGMSetsJoinLevel(t *testing.T) {
+	h := newTestHub(t)
+	level := 50
+	req := joinRequest{TyrantID: "tumba", Level: &level}
+	if h.mayJoin(&Client{role: RolePlayer, user: "ana"}, req) {
+		t.Error("player joined with a level of its choice")
+	}
+	if !h.mayJoin(&Client{role: RoleGM}, req) {
+		t.Error("GM could not set the level")
+	}
+	req.Level = nil
+	if !h.mayJoin(&Client{role: RolePlayer, user: "ana"}, req) {
+		t.Error("player could not join at the default level")
+	}
+}
@@ -0,0 +1,174 @@
+package scene
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRoom is used when a client connects without naming a room, so older
+// clients that only know /scene/ws keep sharing a single table.
+const DefaultRoom = "default"
+
+// RoomIdleTTL is how long an empty room is kept before it is torn down.
+const RoomIdleTTL = 10 * time.Minute
+
+// RoomSummary is the REST view of an active room.
+type RoomSummary struct {
+	ID           string `json:"id"`
+	Participants int    `json:"participants"`
+	Clients      int    `json:"clients"`
+	InBattle     bool   `json:"inBattle"`
+}
+
+// Registry owns one isolated Hub per room. Hubs are created lazily on the
+// first connection and reaped once they have been empty for RoomIdleTTL.
+type Registry struct {
+	mu      sync.Mutex
+	svc     Service
+	newRand RandFactory
+	rooms   map[string]*Hub
+	// refs counts connections currently attached to each hub (guarded by mu)
+	refs    map[*Hub]int
+	idleTTL time.Duration
+}
+
+func NewRegistry(svc Service, newRand RandFactory) *Registry {
+	r := &Registry{
+		svc:     svc,
+		newRand: newRand,
+		rooms:   make(map[string]*Hub),
+		refs:    make(map[*Hub]int),
+		idleTTL: RoomIdleTTL,
+	}
+	r.restoreAll()
+	go r.reapLoop()
+	return r
+}
+
+// restoreAll brings back every room that had a checkpoint when the server stopped.
+func (r *Registry) restoreAll() {
+	states, err := r.svc.ListSceneStates()
+	if err != nil {
+		log.Printf("scene: list checkpoints: %v", err)
+		return
+	}
+	for id, data := range states {
+		h := NewHub(r.svc, r.newRand)
+		h.id = id
+		image, err := r.svc.LoadSceneImage(id)
+		if err != nil {
+			log.Printf("scene: load image %s: %v", id, err)
+		}
+		if err := h.restore(data, image); err != nil {
+			log.Printf("scene: restore checkpoint %s: %v", id, err)
+			continue
+		}
+		h.loaded.Do(func() {})
+		r.rooms[id] = h
+	}
+}
+
+// acquire returns the hub for a room, creating it if needed, and pins it so
+// the reaper cannot tear it down while the caller is using it.
+func (r *Registry) acquire(id string) *Hub {
+	r.mu.Lock()
+	h := r.rooms[id]
+	if h == nil {
+		h = NewHub(r.svc, r.newRand)
+		h.id = id
+		r.rooms[id] = h
+	}
+	r.refs[h]++
+	r.mu.Unlock()
+	// a reaped room may still have a checkpoint waiting in the DB; it is read
+	// outside the registry lock, so only callers of this room wait for it
+	h.loaded.Do(h.loadCheckpoint)
+	return h
+}
+
+func (r *Registry) release(h *Hub) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs[h] > 0 {
+		r.refs[h]--
+	}
+	h.touch()
+}
+
+func (r *Registry) reapLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.reap()
+	}
+}
+
+func (r *Registry) reap() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, h := range r.rooms {
+		if r.refs[h] > 0 || !h.idleFor(r.idleTTL) {
+			continue
+		}
+		h.stopTurnTimer()
+		delete(r.rooms, id)
+		delete(r.refs, h)
+	}
+}
+
+// ServeWS handles /scene/ws?room=<id>; without a room it joins DefaultRoom.
+func (r *Registry) ServeWS(w http.ResponseWriter, req *http.Request) {
+	room := req.URL.Query().Get("room")
+	if room == "" {
+		room = DefaultRoom
+	}
+	if strings.Contains(room, "/") {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	r.serveRoom(w, req, room)
+}
+
+// ServeRoomWS handles /scene/{room}/ws.
+func (r *Registry) ServeRoomWS(w http.ResponseWriter, req *http.Request) {
+	rest := strings.TrimPrefix(req.URL.Path, "/scene/")
+	room, suffix, ok := strings.Cut(rest, "/")
+	if !ok || room == "" || suffix != "ws" {
+		http.NotFound(w, req)
+		return
+	}
+	r.serveRoom(w, req, room)
+}
+
+func (r *Registry) serveRoom(w http.ResponseWriter, req *http.Request, room string) {
+	h := r.acquire(room)
+	defer r.release(h)
+	h.ServeWS(w, req)
+}
+
+// Rooms handles GET /scene/rooms, listing active rooms and their occupancy.
+func (r *Registry) Rooms(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	r.mu.Lock()
+	hubs := make([]*Hub, 0, len(r.rooms))
+	for _, h := range r.rooms {
+		hubs = append(hubs, h)
+	}
+	r.mu.Unlock()
+
+	list := make([]RoomSummary, 0, len(hubs))
+	for _, h := range hubs {
+		list = append(list, h.Summary())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
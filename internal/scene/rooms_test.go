@@ -0,0 +1,49 @@
+package scene
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matheustorresii/tyrants-back/internal/db"
+)
+
+// slowLoadService blocks the checkpoint load of room "slow" until release closes.
+type slowLoadService struct {
+	*stubService
+	release chan struct{}
+}
+
+func (s slowLoadService) LoadSceneState(roomID string) ([]byte, error) {
+	if roomID == "slow" {
+		<-s.release
+	}
+	return nil, db.ErrSceneStateNotFound
+}
+
+func TestAcquireDoesNotWaitForOtherRoomsToLoad(t *testing.T) {
+	svc := slowLoadService{stubService: &stubService{}, release: make(chan struct{})}
+	r := NewRegistry(svc, nil)
+
+	slow := make(chan *Hub, 2)
+	go func() { slow <- r.acquire("slow") }()
+	time.Sleep(20 * time.Millisecond)
+	go func() { slow <- r.acquire("slow") }()
+
+	fast := make(chan *Hub)
+	go func() { fast <- r.acquire("fast") }()
+	select {
+	case <-fast:
+	case <-time.After(time.Second):
+		t.Fatal("acquiring a room waited for another room's checkpoint")
+	}
+	select {
+	case <-slow:
+		t.Fatal("room handed out before its checkpoint was loaded")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(svc.release)
+	if a, b := <-slow, <-slow; a != b {
+		t.Fatal("concurrent acquires of a room got different hubs")
+	}
+}
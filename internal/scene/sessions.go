@@ -0,0 +1,115 @@
+package scene
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+)
+
+// newSessionToken returns a random token that proves a client joined with a
+// given Tyrant.
+func newSessionToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("scene: session token: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// issueSessionLocked hands out a new token for the Tyrant, revoking older ones.
+func (h *Hub) issueSessionLocked(tyrantID string) string {
+	h.revokeSessionLocked(tyrantID)
+	token := newSessionToken()
+	if h.sessions == nil {
+		h.sessions = make(map[string]string)
+	}
+	h.sessions[token] = tyrantID
+	return token
+}
+
+// revokeSessionLocked drops every token of a Tyrant that left the room.
+func (h *Hub) revokeSessionLocked(tyrantID string) {
+	for token, id := range h.sessions {
+		if id == tyrantID {
+			delete(h.sessions, token)
+		}
+	}
+}
+
+// renameSessionLocked points the tokens of from to to, e.g. after an evolution.
+func (h *Hub) renameSessionLocked(from, to string) {
+	for token, id := range h.sessions {
+		if id == from {
+			h.sessions[token] = to
+		}
+	}
+}
+
+// sceneStateLocked is the full view of the room sent to a client that
+// (re)connects or asks for a sync: image, participants (fainted ones too),
+// queue and battle settings.
+func (h *Hub) sceneStateLocked() sceneState {
+	state := sceneState{
+		Tyrants:      h.tyrantsSnapshotLocked(),
+		Turns:        h.turnsViewLocked(),
+		InBattle:     h.inBattle,
+		Mode:         h.mode,
+		TurnDeadline: h.turnDeadlineLocked(),
+	}
+	if h.image != "" {
+		state.Image = h.image
+		state.Fill = h.fill
+	}
+	if h.inBattle || h.votingActive {
+		seed := h.rng.seed
+		turnTimeout := int(h.turnTimeout.Seconds())
+		state.Battle = h.battleStartedWith
+		state.CurrentActor = h.currentActor
+		state.Seed = &seed
+		state.Rules = h.rules
+		state.TurnTimeout = &turnTimeout
+	}
+	if h.votingActive {
+		state.Voting = map[string]int{"UNTIL_DEATH": h.voteUntilDeath, "TO_PARTY": h.voteToParty}
+	}
+	return state
+}
+
+// handleSync sends the full state of the room to a client that suspects it
+// missed updates.
+func (h *Hub) handleSync(c *Client) {
+	h.mu.RLock()
+	state := h.sceneStateLocked()
+	h.mu.RUnlock()
+	_ = c.writeMessage(syncMessage{Sync: state})
+}
+
+// handleResume binds the Tyrant of a session token to the client's new
+// socket and sends it the full current state.
+func (h *Hub) handleResume(c *Client, token string) *errorMessage {
+	h.mu.Lock()
+	id, ok := h.sessions[token]
+	if !ok {
+		h.mu.Unlock()
+		return &errorMessage{Error: "invalid session"}
+	}
+	if h.participants[id] == nil {
+		delete(h.sessions, token)
+		h.mu.Unlock()
+		return &errorMessage{Error: "invalid session"}
+	}
+	if !h.mayResumeLocked(c, id) {
+		h.mu.Unlock()
+		return forbidden()
+	}
+	h.tyrantIDToClient[id] = c
+	if h.inBattle && h.currentActor == id {
+		// control changed hands mid-turn
+		h.armTurnTimerLocked()
+	}
+	payload := resumedMessage{Resumed: id, State: h.sceneStateLocked()}
+	h.mu.Unlock()
+
+	_ = c.writeMessage(payload)
+	return nil
+}
@@ -0,0 +1,264 @@
+package scene
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Status condition names. Attacks inflict them through attributes written as
+// "name", "name:chance" or "name:chance:turns" (e.g. "poison:50:3").
+const (
+	statusPoison    = "poison"
+	statusBurn      = "burn"
+	statusSleep     = "sleep"
+	statusParalysis = "paralysis"
+	statusConfusion = "confusion"
+)
+
+// StatusEffect is a condition currently affecting a participant.
+// Turns is how many of the owner's turns it still lasts; 0 means it lasts
+// until cured or until the battle ends.
+type StatusEffect struct {
+	Turns int
+}
+
+type statusDef struct {
+	chance int // default % chance when inflicted by an attack
+	turns  int // default duration
+	// volatile conditions may stack on top of a major one; major conditions
+	// (poison, burn, sleep, paralysis) are exclusive of each other
+	volatile bool
+}
+
+var statusDefs = map[string]statusDef{
+	statusPoison:    {chance: 30},
+	statusBurn:      {chance: 30},
+	statusSleep:     {chance: 25, turns: 2},
+	statusParalysis: {chance: 30},
+	statusConfusion: {chance: 30, turns: 3, volatile: true},
+}
+
+const (
+	paralysisSkipChance = 25
+	confusionSelfChance = 33
+	confusionSelfPower  = 4
+)
+
+// parseStatusAttribute extracts a status condition from an attack attribute.
+func parseStatusAttribute(attr string) (name string, chance, turns int, ok bool) {
+	parts := strings.Split(attr, ":")
+	def, known := statusDefs[parts[0]]
+	if !known || len(parts) > 3 {
+		return "", 0, 0, false
+	}
+	chance, turns = def.chance, def.turns
+	if len(parts) > 1 {
+		v, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return "", 0, 0, false
+		}
+		chance = v
+	}
+	if len(parts) > 2 {
+		v, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return "", 0, 0, false
+		}
+		turns = v
+	}
+	return parts[0], chance, turns, true
+}
+
+func (p *Participant) hasStatus(name string) bool {
+	_, ok := p.Statuses[name]
+	return ok
+}
+
+// applyStatus adds a condition if the participant can receive it.
+func (p *Participant) applyStatus(name string, turns int) bool {
+	def, ok := statusDefs[name]
+	if !ok || !p.Alive || p.hasStatus(name) {
+		return false
+	}
+	if !def.volatile {
+		for other := range p.Statuses {
+			if !statusDefs[other].volatile {
+				return false
+			}
+		}
+	}
+	if p.Statuses == nil {
+		p.Statuses = make(map[string]*StatusEffect)
+	}
+	p.Statuses[name] = &StatusEffect{Turns: turns}
+	return true
+}
+
+// statusNames returns the participant's conditions in a stable order.
+func (p *Participant) statusNames() []string {
+	names := make([]string, 0, len(p.Statuses))
+	for name := range p.Statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Participant) statusViews() []statusView {
+	out := make([]statusView, 0, len(p.Statuses))
+	for _, name := range p.statusNames() {
+		out = append(out, statusView{Name: name, Turns: p.Statuses[name].Turns})
+	}
+	return out
+}
+
+// inflictStatusesLocked rolls every status attribute of the attack against the target.
+func (h *Hub) inflictStatusesLocked(targetID string, target *Participant, atk *models.Attack) []statusEvent {
+	var events []statusEvent
+	for _, attr := range atk.Attributes {
+		name, chance, turns, ok := parseStatusAttribute(attr)
+		if !ok || !target.Alive {
+			continue
+		}
+		if h.rng.Intn(100) >= chance {
+			continue
+		}
+		if target.applyStatus(name, turns) {
+			events = append(events, statusEvent{ID: targetID, Status: name, Event: "applied"})
+		}
+	}
+	return events
+}
+
+// tickStatusesLocked runs start-of-turn effects and reports whether the
+// participant loses this turn.
+func (h *Hub) tickStatusesLocked(id string, p *Participant) (skip bool, events []statusEvent) {
+	for _, name := range p.statusNames() {
+		switch name {
+		case statusPoison, statusBurn:
+			div := 8
+			if name == statusBurn {
+				div = 16
+			}
+			dmg := p.FullHP / div
+			if dmg < 1 {
+				dmg = 1
+			}
+			h.damageLocked(p, dmg)
+			events = append(events, statusEvent{ID: id, Status: name, Event: "damage", Damage: dmg})
+			if !p.Alive {
+				return true, events
+			}
+		case statusSleep:
+			skip = true
+			events = append(events, statusEvent{ID: id, Status: name, Event: "skipped"})
+		case statusParalysis:
+			if !skip && h.rng.Intn(100) < paralysisSkipChance {
+				skip = true
+				events = append(events, statusEvent{ID: id, Status: name, Event: "skipped"})
+			}
+		}
+	}
+	return skip, events
+}
+
+// expireStatusesLocked counts down timed conditions at the end of the owner's turn.
+func (h *Hub) expireStatusesLocked(id string) []statusEvent {
+	p := h.participants[id]
+	if p == nil {
+		return nil
+	}
+	var events []statusEvent
+	for _, name := range p.statusNames() {
+		st := p.Statuses[name]
+		if st.Turns <= 0 {
+			continue
+		}
+		st.Turns--
+		if st.Turns == 0 {
+			delete(p.Statuses, name)
+			events = append(events, statusEvent{ID: id, Status: name, Event: "cured"})
+		}
+	}
+	return events
+}
+
+// confusionSelfHitLocked makes a confused attacker hurt itself instead of acting.
+func (h *Hub) confusionSelfHitLocked(p *Participant) int {
+	random := h.rng.Intn(100) + 1
+	dmg := (p.Tyrant.Attack*(random+confusionSelfPower*10) - p.Tyrant.Defense) / 200
+	if dmg < 1 {
+		dmg = 1
+	}
+	h.damageLocked(p, dmg)
+	return dmg
+}
+
+// damageLocked subtracts HP and handles fainting, which clears every condition
+// and applies the consequences of the battle mode.
+func (h *Hub) damageLocked(p *Participant, dmg int) {
+	p.CurrentHP -= dmg
+	if p.CurrentHP <= 0 {
+		p.CurrentHP = 0
+		p.Statuses = nil
+		if p.Alive {
+			p.Alive = false
+			h.faintedLocked(p)
+		}
+	}
+}
+
+// handleStatus lets the GM apply or clear a condition manually.
+func (h *Hub) handleStatus(cmd statusCommand) *errorMessage {
+	h.mu.Lock()
+	p := h.participants[cmd.Target]
+	if p == nil {
+		h.mu.Unlock()
+		return &errorMessage{Error: "target not found"}
+	}
+	var events []statusEvent
+	switch {
+	case cmd.Apply != "":
+		def, ok := statusDefs[cmd.Apply]
+		if !ok {
+			h.mu.Unlock()
+			return &errorMessage{Error: "unknown status"}
+		}
+		turns := def.turns
+		if cmd.Turns != nil {
+			if *cmd.Turns < 0 {
+				h.mu.Unlock()
+				return &errorMessage{Error: "invalid turns"}
+			}
+			turns = *cmd.Turns
+		}
+		if !p.applyStatus(cmd.Apply, turns) {
+			h.mu.Unlock()
+			return &errorMessage{Error: "status cannot be applied"}
+		}
+		events = append(events, statusEvent{ID: cmd.Target, Status: cmd.Apply, Event: "applied"})
+	case cmd.Clear == "all":
+		for _, name := range p.statusNames() {
+			events = append(events, statusEvent{ID: cmd.Target, Status: name, Event: "cured"})
+		}
+		p.Statuses = nil
+	case cmd.Clear != "":
+		if !p.hasStatus(cmd.Clear) {
+			h.mu.Unlock()
+			return &errorMessage{Error: "status not present"}
+		}
+		delete(p.Statuses, cmd.Clear)
+		events = append(events, statusEvent{ID: cmd.Target, Status: cmd.Clear, Event: "cured"})
+	default:
+		h.mu.Unlock()
+		return &errorMessage{Error: "invalid status command"}
+	}
+	h.logEventLocked("status", map[string]any{"statusEvents": events})
+	payload := h.updateLocked(h.stateLocked(events))
+	h.mu.Unlock()
+	h.broadcast(payload)
+	return nil
+}
@@ -0,0 +1,15 @@
+package scene
+
+import "testing"
+
+func TestStatusRefusesNegativeTurns(t *testing.T) {
+	h := newTestHub(t, joinRequest{TyrantID: "tumba"})
+	turns := -1
+	failure := h.handleStatus(statusCommand{Target: "tumba", Apply: statusSleep, Turns: &turns})
+	if failure == nil || failure.Error != "invalid turns" {
+		t.Fatalf("failure = %+v, want invalid turns", failure)
+	}
+	if h.participants["tumba"].hasStatus(statusSleep) {
+		t.Fatal("sleep applied with negative turns")
+	}
+}
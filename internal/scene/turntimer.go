@@ -0,0 +1,85 @@
+package scene
+
+import (
+	"time"
+)
+
+// armTurnTimerLocked starts whatever the new turn waits on: the move of a
+// computer-controlled actor after the AI delay, or the countdown for a player
+// when the battle has a turn timeout. The previous turn's timer is cancelled.
+func (h *Hub) armTurnTimerLocked() {
+	h.stopTurnTimerLocked()
+	if !h.inBattle || h.currentActor == "" {
+		return
+	}
+	gen := h.turnGen
+	if p := h.participants[h.currentActor]; p != nil && p.AI != "" {
+		h.turnTimer = time.AfterFunc(h.aiDelay, func() { h.aiTurn(gen) })
+		return
+	}
+	if h.turnTimeout <= 0 {
+		return
+	}
+	h.turnDeadline = time.Now().Add(h.turnTimeout)
+	h.turnTimer = time.AfterFunc(h.turnTimeout, func() { h.turnExpired(gen) })
+}
+
+// stopTurnTimerLocked cancels the countdown. Bumping turnGen also discards a
+// timer that already fired and is waiting for the lock.
+func (h *Hub) stopTurnTimerLocked() {
+	h.turnGen++
+	if h.turnTimer != nil {
+		h.turnTimer.Stop()
+		h.turnTimer = nil
+	}
+	h.turnDeadline = time.Time{}
+}
+
+// stopTurnTimer cancels the countdown of a hub that is going away.
+func (h *Hub) stopTurnTimer() {
+	h.mu.Lock()
+	h.stopTurnTimerLocked()
+	h.mu.Unlock()
+}
+
+// turnDeadlineLocked returns the current turn deadline, or "" without one.
+func (h *Hub) turnDeadlineLocked() string {
+	if h.turnDeadline.IsZero() {
+		return ""
+	}
+	return h.turnDeadline.UTC().Format(time.RFC3339Nano)
+}
+
+// turnExpired skips the turn of an actor that did not act in time.
+func (h *Hub) turnExpired(gen uint64) {
+	h.mu.Lock()
+	if gen != h.turnGen || !h.inBattle || h.currentActor == "" {
+		h.mu.Unlock()
+		return
+	}
+	if len(h.clients) == 0 {
+		// nobody at the table (e.g. right after a restart): wait for them
+		h.armTurnTimerLocked()
+		h.mu.Unlock()
+		return
+	}
+	actor := h.currentActor
+	payload := h.skipTurnLocked(actor, "timeout")
+	payload.TurnExpired = actor
+	h.mu.Unlock()
+
+	h.broadcast(payload)
+	h.persist()
+}
+
+// skipTurnLocked ends actor's turn without an action, logging it as logType,
+// and returns the update to broadcast; callers name the actor in it.
+func (h *Hub) skipTurnLocked(actor, logType string) updateMessage {
+	h.logEventLocked(logType, map[string]any{"id": actor})
+	status, events := h.endTurnLocked(actor, nil)
+	if status == nil {
+		state := h.stateLocked(events)
+		status = &state
+	}
+	return h.updateLocked(*status)
+}
@@ -0,0 +1,51 @@
+package scene
+
+import "github.com/matheustorresii/tyrants-back/internal/models"
+
+// typeChart indexes the effectiveness matrix as attribute -> defender type -> multiplier.
+type typeChart map[string]map[string]float64
+
+func newTypeChart(entries []models.TypeEffectiveness) typeChart {
+	c := make(typeChart, len(entries))
+	for _, e := range entries {
+		row := c[e.Attribute]
+		if row == nil {
+			row = make(map[string]float64)
+			c[e.Attribute] = row
+		}
+		row[e.DefenderType] = e.Multiplier
+	}
+	return c
+}
+
+// multiplier combines every (attack attribute, defender type) pair found in the
+// chart. Attributes that are not elemental simply have no row and stay neutral.
+func (c typeChart) multiplier(attributes, defenderTypes []string) float64 {
+	m := 1.0
+	for _, attr := range attributes {
+		row := c[attr]
+		if row == nil {
+			continue
+		}
+		for _, typ := range defenderTypes {
+			if v, ok := row[typ]; ok {
+				m *= v
+			}
+		}
+	}
+	return m
+}
+
+// effectivenessLabel is the text clients show next to the damage.
+func effectivenessLabel(m float64) string {
+	switch {
+	case m == 0:
+		return "no effect"
+	case m > 1:
+		return "super effective"
+	case m < 1:
+		return "not very effective"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,141 @@
+package scene
+
+// tyrantView is the HP/PP/status snapshot of a combatant sent to clients.
+type tyrantView struct {
+	ID        string         `json:"id"`
+	Tyrant    string         `json:"tyrant"`
+	Name      string         `json:"name"`
+	FullHP    int            `json:"fullHp"`
+	CurrentHP int            `json:"currentHp"`
+	Asset     string         `json:"asset"`
+	Enemy     bool           `json:"enemy"`
+	Alive     bool           `json:"alive"`
+	Level     int            `json:"level"`
+	Attacks   []attackPPView `json:"attacks"`
+	Status    []statusView   `json:"status"`
+	Defending bool           `json:"defending"`
+	Buffs     map[string]int `json:"buffs"`
+}
+
+// attackPPView is the PP left of one attack.
+type attackPPView struct {
+	Name      string `json:"name"`
+	FullPP    int    `json:"fullPP"`
+	CurrentPP int    `json:"currentPP"`
+}
+
+// statusView is a condition affecting a combatant and the turns it still lasts.
+type statusView struct {
+	Name  string `json:"name"`
+	Turns int    `json:"turns"`
+}
+
+// turnView is one entry of the turn queue.
+type turnView struct {
+	ID     string `json:"id"`
+	Tyrant string `json:"tyrant"`
+	Name   string `json:"name"`
+	Asset  string `json:"asset"`
+	Enemy  bool   `json:"enemy"`
+}
+
+// statusEvent is something that happened to a condition this turn: applied,
+// damage, skipped, selfHit or cured.
+type statusEvent struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Event  string `json:"event"`
+	// Damage is the HP lost on damage and selfHit events
+	Damage int `json:"damage,omitempty"`
+}
+
+// stateUpdate is the updateState of a turn: the snapshot and what was just
+// done while the battle goes on, or the outcome once it ends.
+type stateUpdate struct {
+	Outcome string `json:"outcome,omitempty"`
+	// XPAwarded is the XP breakdown of a win
+	XPAwarded    []xpAward     `json:"xpAwarded,omitempty"`
+	Tyrants      []tyrantView  `json:"tyrants,omitempty"`
+	StatusEvents []statusEvent `json:"statusEvents,omitempty"`
+	Mode         string        `json:"mode,omitempty"`
+	// at most one of these describes the move that changed the state
+	LastAttack   *attackResult   `json:"lastAttack,omitempty"`
+	LastAction   *actionResult   `json:"lastAction,omitempty"`
+	LastItem     *itemResult     `json:"lastItem,omitempty"`
+	LastOverride *overrideResult `json:"lastOverride,omitempty"`
+}
+
+// attackResult is what an attack did. Single target moves carry the result
+// of the hit themselves; area moves list one result per target in Targets,
+// in the same format.
+type attackResult struct {
+	User     string `json:"user,omitempty"`
+	Attack   string `json:"attack,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Confused is set when the attacker hurt itself instead
+	Confused      bool          `json:"confused,omitempty"`
+	Hit           *HitRoll      `json:"hit,omitempty"`
+	Missed        bool          `json:"missed,omitempty"`
+	Effectiveness *float64      `json:"effectiveness,omitempty"`
+	Damage        *DamageResult `json:"damage,omitempty"`
+	Effect        string        `json:"effect,omitempty"`
+	// support moves report their effect instead of damage
+	Healed  *int           `json:"healed,omitempty"`
+	Revived int            `json:"revived,omitempty"`
+	Buff    *buffResult    `json:"buff,omitempty"`
+	Targets []attackResult `json:"targets,omitempty"`
+}
+
+// buffResult is the stat stage change of a buff move.
+type buffResult struct {
+	Stat   string `json:"stat"`
+	Change int    `json:"change"`
+	Stages int    `json:"stages"`
+}
+
+// actionResult is what a non-attack action did; a struggle also carries the
+// result of the hit.
+type actionResult struct {
+	attackResult
+	Type   string `json:"type"`
+	Fled   *bool  `json:"fled,omitempty"`
+	Recoil int    `json:"recoil,omitempty"`
+}
+
+// itemResult is the item used and its effect.
+type itemResult struct {
+	User      string `json:"user"`
+	Target    string `json:"target"`
+	Item      string `json:"item"`
+	Remaining int    `json:"remaining"`
+	itemEffect
+}
+
+// overrideResult is the GM correction applied and the value it set.
+type overrideResult struct {
+	Type   string   `json:"type"`
+	Target string   `json:"target,omitempty"`
+	HP     *int     `json:"hp,omitempty"`
+	Attack string   `json:"attack,omitempty"`
+	PP     *int     `json:"pp,omitempty"`
+	Order  []string `json:"order,omitempty"`
+}
+
+// sceneState is the full view of the room sent on connect, sync and resume.
+// The battle settings are only set during a battle or its vote.
+type sceneState struct {
+	Image        string         `json:"image,omitempty"`
+	Fill         *bool          `json:"fill,omitempty"`
+	Tyrants      []tyrantView   `json:"tyrants"`
+	Turns        []turnView     `json:"turns"`
+	InBattle     bool           `json:"inBattle"`
+	Battle       string         `json:"battle,omitempty"`
+	CurrentActor string         `json:"currentActor,omitempty"`
+	Seed         *int64         `json:"seed,omitempty"`
+	Rules        string         `json:"rules,omitempty"`
+	TurnTimeout  *int           `json:"turnTimeout,omitempty"`
+	Voting       map[string]int `json:"voting,omitempty"`
+	Mode         string         `json:"mode,omitempty"`
+	TurnDeadline string         `json:"turnDeadline,omitempty"`
+}
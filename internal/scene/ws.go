@@ -1,25 +1,44 @@
 package scene
 
 import (
-	"encoding/json"
 	"log"
-	"math/rand"
 	"net/http"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/matheustorresii/tyrants-back/internal/auth"
 	"github.com/matheustorresii/tyrants-back/internal/models"
 )
 
-// TyrantService defines the DB dependency we need.
-type TyrantService interface {
+// Service defines the DB dependencies the scene needs.
+type Service interface {
+	auth.UserLookup
 	GetTyrant(id string) (models.Tyrant, error)
+	ListTypeChart() ([]models.TypeEffectiveness, error)
+	SaveSceneState(roomID string, state []byte) error
+	LoadSceneState(roomID string) ([]byte, error)
+	SaveSceneImage(roomID string, image []byte) error
+	LoadSceneImage(roomID string) ([]byte, error)
+	ListSceneStates() (map[string][]byte, error)
+	DeleteSceneState(roomID string) error
+	CreateBattle(b models.Battle) error
+	AppendBattleEvent(battleID string, e models.BattleEvent) error
+	FinishBattle(id, outcome, endedAt string) error
+	UpdateUser(id string, upd models.UserUpdate) (models.UserDetails, error)
+	ConsumeUserItem(userID, name string) (int, error)
+	AwardXP(awards map[string]int) error
 }
 
 type Participant struct {
+	// ID is the combatant id the participant is keyed by (e.g. "tumba#2");
+	// Tyrant.ID is its species
+	ID        string
+	Name      string
 	Tyrant    models.Tyrant
 	Enemy     bool
+	Level     int
 	FullHP    int
 	CurrentHP int
 	Alive     bool
@@ -28,22 +47,39 @@ type Participant struct {
 		Full    int
 		Current int
 	}
-}
-
-type Client struct {
-	conn *websocket.Conn
+	// Status conditions currently affecting this participant, by name
+	Statuses map[string]*StatusEffect
+	// User that joined with this Tyrant, if known
+	Owner string
+	// AI strategy playing this participant; empty when a client controls it
+	AI string
+	// HP taken from the other side during the current battle, for XP shares
+	DamageDealt int
+	// Defending halves the damage taken until this participant's next turn
+	Defending bool
+	// Stat stages from buff moves during the current battle, by stat
+	Buffs map[string]int
 }
 
 type Hub struct {
 	mu               sync.RWMutex
-	svc              TyrantService
+	id               string
+	svc              Service
+	lastActive       time.Time
 	clients          map[*Client]bool
 	tyrantIDToClient map[string]*Client
-	participants     map[string]*Participant // key: tyrant id
+	participants     map[string]*Participant // key: combatant id
 	turnOrder        []string                // ordered tyrant IDs by speed desc
 	turnIndex        int
 	inBattle         bool
 	currentActor     string
+	// reconnect tokens handed out on join: token -> combatant id
+	sessions map[string]string
+	// scene presentation: the last image shown and how it fills the screen;
+	// imageSeq counts changes so checkpoints only rewrite it when it changed
+	image    string
+	fill     *bool
+	imageSeq uint64
 	// battle start identifier (who starts)
 	battleStartedWith string
 	// voting state
@@ -52,28 +88,111 @@ type Hub struct {
 	voteToParty    int
 	votedAllies    map[string]string
 	totalAllies    int
+	// mode decided by the vote; empty when the battle started without voting
+	mode string
+	// optional turn countdown and AI pacing; turnGen invalidates timers
+	// from older turns
+	aiDelay      time.Duration
+	turnTimeout  time.Duration
+	turnTimer    *time.Timer
+	turnDeadline time.Time
+	turnGen      uint64
+	// elemental chart loaded when the battle starts
+	typeChart typeChart
+	// damage rule set of the current battle
+	rules  string
+	damage DamageCalculator
+	// every roll of the current battle comes from rng, seeded per battle
+	newRand RandFactory
+	rng     *battleRand
+	// battle log: current battle id, last event seq and writes pending flush
+	battleID string
+	eventSeq int
+	logOps   []func() error
+	logMu    sync.Mutex
+	// owner updates (e.g. UNTIL_DEATH losses) pending flush
+	userOps []func() error
+	// loaded runs the checkpoint load of a hub created on demand, once
+	loaded sync.Once
+	// checkpoint ordering: saveSeq is bumped under mu, savedSeq under saveMu
+	saveMu        sync.Mutex
+	saveSeq       uint64
+	savedSeq      uint64
+	savedImageSeq uint64
 }
 
-func NewHub(svc TyrantService) *Hub {
+// NewHub creates an empty scene. newRand builds each battle's random source
+// from its seed; nil uses NewMathRand.
+func NewHub(svc Service, newRand RandFactory) *Hub {
+	if newRand == nil {
+		newRand = NewMathRand
+	}
 	return &Hub{
 		svc:              svc,
+		newRand:          newRand,
+		rules:            RulesClassic,
+		damage:           classicDamage{},
+		aiDelay:          DefaultAIDelay,
+		rng:              newBattleRand(newRand, timeSeed()),
 		clients:          make(map[*Client]bool),
 		tyrantIDToClient: make(map[string]*Client),
+		sessions:         make(map[string]string),
 		participants:     make(map[string]*Participant),
+		lastActive:       time.Now(),
+	}
+}
+
+// touch marks the hub as recently used so the registry keeps it alive.
+func (h *Hub) touch() {
+	h.mu.Lock()
+	h.lastActive = time.Now()
+	h.mu.Unlock()
+}
+
+// idleFor reports whether the hub has had no clients for at least d.
+func (h *Hub) idleFor(d time.Duration) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients) == 0 && time.Since(h.lastActive) >= d
+}
+
+// Summary returns the room occupancy shown by GET /scene/rooms.
+func (h *Hub) Summary() RoomSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return RoomSummary{
+		ID:           h.id,
+		Participants: len(h.participants),
+		Clients:      len(h.clients),
+		InBattle:     h.inBattle || h.votingActive,
 	}
 }
 
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	version, ok := negotiateVersion(r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	role, userID, status := h.authenticate(r)
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "upgrade failed", http.StatusBadRequest)
 		return
 	}
-	client := &Client{conn: conn}
+	client := newClient(conn, role, userID, version)
 	h.mu.Lock()
 	h.clients[client] = true
+	h.lastActive = time.Now()
+	// queued under the lock so no broadcast can reach the client before them
+	_ = client.writeMessage(connectedMessage{Connected: connectedInfo{Role: role, User: userID, Version: version}})
+	_ = client.writeMessage(syncMessage{Sync: h.sceneStateLocked()})
 	h.mu.Unlock()
 
 	// Clean up on close
@@ -86,117 +205,183 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 				delete(h.tyrantIDToClient, id)
 			}
 		}
+		h.lastActive = time.Now()
 		h.mu.Unlock()
-		_ = conn.Close()
+		client.close()
 	}()
 
-	for {
-		_, data, err := conn.ReadMessage()
-		if err != nil {
-			return
-		}
-		h.handleIncoming(client, data)
-	}
+	client.readLoop(func(data []byte) { h.handleIncoming(client, data) })
 }
 
 // Incoming message shapes
 type attackEvent struct {
 	User   string `json:"user"`
 	Target string `json:"target"`
-	Attack string `json:"attack"`
+	// Targets lists the targets of an area attack; see resolveTargetsLocked
+	Targets []string `json:"targets,omitempty"`
+	Attack  string   `json:"attack"`
 }
 
-type incoming struct {
-	Image         *string      `json:"image,omitempty"`
-	Fill          *bool        `json:"fill,omitempty"`
-	Battle        *string      `json:"battle,omitempty"`
-	VoteEnabled   *bool        `json:"voteEnabled,omitempty"`
-	Join          *string      `json:"join,omitempty"`
-	Enemy         *bool        `json:"enemy,omitempty"`
-	Attack        *attackEvent `json:"attack,omitempty"`
-	Clean         *bool        `json:"clean,omitempty"`
-	IncludeAllies *bool        `json:"includeAllies,omitempty"`
-	Leave         *string      `json:"leave,omitempty"`
-	Vote          *string      `json:"vote,omitempty"`
-	User          *string      `json:"user,omitempty"`
+// statusCommand is the GM's manual status control.
+type statusCommand struct {
+	Target string `json:"target"`
+	Apply  string `json:"apply,omitempty"`
+	Clear  string `json:"clear,omitempty"`
+	Turns  *int   `json:"turns,omitempty"`
 }
 
+// handleIncoming decodes a message in the client's protocol version, runs it
+// and answers its request id. Versioned clients get an error for anything
+// that does not decode; legacy ones keep having those ignored unless they
+// sent an id.
 func (h *Hub) handleIncoming(c *Client, data []byte) {
-	var msg incoming
-	if err := json.Unmarshal(data, &msg); err != nil {
-		log.Printf("scene: invalid message: %v", err)
+	var cmd command
+	if c.version == LegacyProtocol {
+		var ok bool
+		if cmd, ok = decodeLegacy(data); !ok {
+			if cmd.ID != "" {
+				c.reply(cmd.ID, &errorMessage{Error: errUnknownType.Error()})
+			}
+			return
+		}
+	} else {
+		var err error
+		if cmd, err = decodeEnvelope(data, c.version); err != nil {
+			c.reply(cmd.ID, &errorMessage{Error: err.Error()})
+			return
+		}
+	}
+	c.reply(cmd.ID, h.dispatch(c, cmd))
+}
+
+// reply answers request id with an ack, or with failure when the request was
+// refused. Errors are sent even without an id; acks only to requests that
+// carry one.
+func (c *Client) reply(id string, failure *errorMessage) {
+	if failure != nil {
+		failure.ID = id
+		_ = c.writeMessage(*failure)
 		return
 	}
+	if id != "" {
+		_ = c.writeMessage(ackMessage{Ack: id})
+	}
+}
 
-	switch {
-	case msg.Image != nil:
-		payload := map[string]any{"image": *msg.Image}
-		if msg.Fill != nil {
-			payload["fill"] = *msg.Fill
+// dispatch checks the sender may send cmd and hands it to its handler. It
+// returns why the command was refused, or nil once it was carried out.
+func (h *Hub) dispatch(c *Client, cmd command) *errorMessage {
+	var failure *errorMessage
+	switch msg := cmd.Payload.(type) {
+	case *imagePayload:
+		if c.role != RoleGM {
+			return forbidden()
 		}
-		h.broadcast(payload)
-	case msg.Join != nil:
-		h.handleJoin(c, *msg.Join, msg.Enemy)
-	case msg.Battle != nil:
-		voteEnabled := false
-		if msg.VoteEnabled != nil {
-			voteEnabled = *msg.VoteEnabled
-		}
-		h.handleBattle(*msg.Battle, voteEnabled)
-	case msg.Attack != nil:
-		h.handleAttack(*msg.Attack)
-	case msg.Clean != nil && *msg.Clean:
-		includeAllies := false
-		if msg.IncludeAllies != nil {
-			includeAllies = *msg.IncludeAllies
-		}
-		h.handleClean(includeAllies)
-	case msg.Leave != nil:
-		allyID := *msg.Leave
+		h.mu.Lock()
+		h.image, h.fill = msg.Image, msg.Fill
+		h.imageSeq++
+		h.mu.Unlock()
+		h.broadcast(imageMessage{Image: msg.Image, Fill: msg.Fill})
+	case *syncPayload:
+		h.handleSync(c)
+		return nil
+	case *joinRequest:
+		req := *msg
+		if !h.mayJoin(c, req) {
+			return forbidden()
+		}
+		if req.User == nil && c.role == RolePlayer {
+			// players always join as themselves
+			req.User = &c.user
+		}
+		failure = h.handleJoin(c, req)
+	case *battlePayload:
+		if c.role != RoleGM {
+			return forbidden()
+		}
+		failure = h.handleBattle(msg.setup())
+	case *attackEvent:
+		if !h.mayActFor(c, msg.User) {
+			return forbidden()
+		}
+		failure = h.handleAttack(*msg)
+	case *itemEvent:
+		if !h.mayActFor(c, msg.User) {
+			return forbidden()
+		}
+		failure = h.handleItem(*msg)
+	case *actionEvent:
+		if !h.mayActFor(c, msg.User) {
+			return forbidden()
+		}
+		failure = h.handleAction(*msg)
+	case *statusCommand:
+		if c.role != RoleGM {
+			return forbidden()
+		}
+		failure = h.handleStatus(*msg)
+	case *overrideCommand:
+		if c.role != RoleGM {
+			return forbidden()
+		}
+		failure = h.handleOverride(*msg)
+	case *cleanPayload:
+		if c.role != RoleGM {
+			return forbidden()
+		}
+		h.handleClean(msg.IncludeAllies)
+	case *resumePayload:
+		return h.handleResume(c, msg.Token)
+	case *leavePayload:
+		allyID := msg.Tyrant
 		if allyID == "" {
-			if msg.User != nil {
-				allyID = *msg.User
-			} else {
-				for id, cli := range h.tyrantIDToClient {
-					if cli == c {
-						allyID = id
-						break
-					}
-				}
-			}
+			allyID = h.boundTyrant(c)
 		}
-		h.handleLeave(c, allyID)
-	case msg.Vote != nil:
-		var voter string
-		if msg.User != nil {
-			voter = *msg.User
-		} else {
-			for id, cli := range h.tyrantIDToClient {
-				if cli == c {
-					voter = id
-					break
-				}
-			}
+		if !h.mayActFor(c, allyID) {
+			return forbidden()
+		}
+		failure = h.handleLeave(allyID)
+	case *votePayload:
+		voter := msg.User
+		if voter == "" {
+			voter = h.boundTyrant(c)
 		}
-		h.handleVote(c, voter, *msg.Vote)
+		if !h.mayActFor(c, voter) {
+			return forbidden()
+		}
+		failure = h.handleVote(voter, msg.Choice)
 	default:
-		// ignore
+		return &errorMessage{Error: errUnknownType.Error()}
 	}
+	// every other message may have changed battle state
+	h.persist()
+	return failure
 }
 
 func (h *Hub) handleClean(includeAllies bool) {
 	h.mu.Lock()
+	if h.battleID != "" {
+		h.logEventLocked("clean", map[string]any{"includeAllies": includeAllies})
+		h.endBattleLogLocked("CLEANED")
+	}
 	// stop battle
 	h.inBattle = false
+	h.votingActive = false
 	h.currentActor = ""
-	// remove only enemies
+	h.stopTurnTimerLocked()
+	// remove only enemies (and allies lost UNTIL_DEATH)
 	for id, p := range h.participants {
-		if p.Enemy || includeAllies {
+		if p.Enemy || includeAllies || h.lostLocked(p) {
 			delete(h.participants, id)
 			delete(h.tyrantIDToClient, id)
+			h.revokeSessionLocked(id)
 		} else {
-			// reset ally HP/PP for next battle readiness
+			// reset ally HP/PP/status for next battle readiness
 			p.CurrentHP = p.FullHP
+			p.Alive = p.FullHP > 0
+			p.Statuses = nil
+			p.Defending = false
+			p.Buffs = nil
 			for _, v := range p.AttackPP {
 				if v != nil {
 					v.Current = v.Full
@@ -204,25 +389,40 @@ func (h *Hub) handleClean(includeAllies bool) {
 			}
 		}
 	}
+	h.mode = ""
 	h.computeTurnOrderLocked()
 	turns := h.turnsViewLocked()
 	h.mu.Unlock()
 
-	h.broadcast(map[string]any{"clean": true, "turns": turns})
+	h.broadcast(cleanMessage{Clean: true, Turns: turns})
 }
 
-func (h *Hub) handleLeave(c *Client, allyID string) {
+func (h *Hub) handleLeave(allyID string) *errorMessage {
 	h.mu.Lock()
 	p := h.participants[allyID]
 	if p == nil || p.Enemy {
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "ally not found"})
-		}
-		return
+		return &errorMessage{Error: "ally not found"}
 	}
 	delete(h.participants, allyID)
 	delete(h.tyrantIDToClient, allyID)
+	h.revokeSessionLocked(allyID)
+	h.logEventLocked("leave", map[string]any{"id": allyID})
+	// keep turnIndex on the same next combatant once the leaver is out of the order
+	for i, id := range h.turnOrder {
+		if id == allyID {
+			if i < h.turnIndex {
+				h.turnIndex--
+			}
+			break
+		}
+	}
+	h.computeTurnOrderLocked()
+	// the leaver may hold the turn already picked while voting is open
+	var events []statusEvent
+	if (h.inBattle || h.votingActive) && h.currentActor == allyID {
+		events = h.advanceTurnLocked()
+	}
 	// adjust voting if active
 	if h.votingActive {
 		if prev, ok := h.votedAllies[allyID]; ok {
@@ -247,63 +447,32 @@ func (h *Hub) handleLeave(c *Client, allyID string) {
 		}
 		if len(h.votedAllies) >= h.totalAllies {
 			// finalize voting
-			h.votingActive = false
-			h.inBattle = true
-			// tie -> TO_PARTY
-			result := "TO_PARTY"
-			if h.voteUntilDeath > h.voteToParty {
-				result = "UNTIL_DEATH"
-			}
-			_ = result
+			result := h.voteResultLocked()
 			counts := map[string]int{"UNTIL_DEATH": h.voteUntilDeath, "TO_PARTY": h.voteToParty}
-			// snapshot tyrants (only alive participants)
-			tyrantUpdates := make([]map[string]any, 0, len(h.participants))
-			for id, pr := range h.participants {
-				if pr == nil || !pr.Alive {
-					continue
-				}
-				attacksArr := make([]map[string]any, 0, len(pr.AttackPP))
-				for name, v := range pr.AttackPP {
-					attacksArr = append(attacksArr, map[string]any{"name": name, "fullPP": v.Full, "currentPP": v.Current})
-				}
-				tyrantUpdates = append(tyrantUpdates, map[string]any{
-					"id": id, "fullHp": pr.FullHP, "currentHp": pr.CurrentHP, "attacks": attacksArr,
-				})
-			}
+			h.logEventLocked("voteResult", map[string]any{"voting": counts, "result": result})
+			payload := h.battleStartPayloadLocked(counts)
 			h.mu.Unlock()
-			turns := h.turnsViewLocked()
-			h.broadcast(map[string]any{"battle": h.battleStartedWith, "turns": turns, "voting": counts, "tyrants": tyrantUpdates})
-			return
+			h.broadcast(payload)
+			return nil
 		}
 	}
-	// recompute order and possibly current actor
-	h.computeTurnOrderLocked()
-	if h.inBattle && h.currentActor == allyID {
-		next := h.nextAliveLocked()
-		h.currentActor = next
-	}
-	turns := h.turnsViewLocked()
+	payload := leftMessage{Left: allyID, Turns: h.turnsViewLocked(), StatusEvents: events, TurnDeadline: h.turnDeadlineLocked()}
 	h.mu.Unlock()
-	h.broadcast(map[string]any{"left": allyID, "turns": turns})
+	h.broadcast(payload)
+	return nil
 }
 
-func (h *Hub) handleVote(c *Client, voterID string, choice string) {
+func (h *Hub) handleVote(voterID string, choice string) *errorMessage {
 	h.mu.Lock()
 	if !h.votingActive {
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "voting not active"})
-		}
-		return
+		return &errorMessage{Error: "voting not active"}
 	}
 	// only allies can vote
 	p := h.participants[voterID]
 	if p == nil || p.Enemy {
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "only allies can vote"})
-		}
-		return
+		return &errorMessage{Error: "only allies can vote"}
 	}
 	prev, hasPrev := h.votedAllies[voterID]
 	// decrement previous choice if changing vote
@@ -327,67 +496,79 @@ func (h *Hub) handleVote(c *Client, voterID string, choice string) {
 		h.voteToParty++
 	default:
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "invalid vote"})
-		}
-		return
+		return &errorMessage{Error: "invalid vote"}
 	}
 	h.votedAllies[voterID] = choice
+	h.logEventLocked("vote", map[string]any{"user": voterID, "choice": choice})
 	counts := map[string]int{"UNTIL_DEATH": h.voteUntilDeath, "TO_PARTY": h.voteToParty}
 	done := len(h.votedAllies) >= h.totalAllies
 	if done {
-		h.votingActive = false
-		h.inBattle = true
-		// tie -> TO_PARTY
-		result := "TO_PARTY"
-		if h.voteUntilDeath > h.voteToParty {
-			result = "UNTIL_DEATH"
-		}
-		_ = result
-		// build tyrants snapshot (only alive participants)
-		tyrantUpdates := make([]map[string]any, 0, len(h.participants))
-		for id, p := range h.participants {
-			if p == nil || !p.Alive {
-				continue
-			}
-			attacksArr := make([]map[string]any, 0, len(p.AttackPP))
-			for name, v := range p.AttackPP {
-				attacksArr = append(attacksArr, map[string]any{"name": name, "fullPP": v.Full, "currentPP": v.Current})
-			}
-			tyrantUpdates = append(tyrantUpdates, map[string]any{
-				"id":        id,
-				"fullHp":    p.FullHP,
-				"currentHp": p.CurrentHP,
-				"asset":     p.Tyrant.Asset,
-				"enemy":     p.Enemy,
-				"attacks":   attacksArr,
-			})
-		}
+		result := h.voteResultLocked()
+		h.logEventLocked("voteResult", map[string]any{"voting": counts, "result": result})
+		payload := h.battleStartPayloadLocked(counts)
 		h.mu.Unlock()
-		turns := h.turnsViewLocked()
-		h.broadcast(map[string]any{"battle": h.battleStartedWith, "turns": turns, "voting": counts, "tyrants": tyrantUpdates})
-		return
+		h.broadcast(payload)
+		return nil
 	}
 	h.mu.Unlock()
-	h.broadcast(map[string]any{"voting": counts})
+	h.broadcast(votingMessage{Voting: counts})
+	return nil
 }
 
-func (h *Hub) handleJoin(c *Client, tyrantID string, enemy *bool) {
-	t, err := h.svc.GetTyrant(tyrantID)
+// joinRequest holds the options of a join message.
+type joinRequest struct {
+	TyrantID string `json:"tyrant"`
+	// Instance picks an existing combatant of the species to take over
+	Instance *string `json:"instance,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Enemy    *bool   `json:"enemy,omitempty"`
+	Level    *int    `json:"level,omitempty"`
+	User     *string `json:"user,omitempty"`
+	AI       *string `json:"ai,omitempty"`
+}
+
+func (h *Hub) handleJoin(c *Client, req joinRequest) *errorMessage {
+	if req.AI != nil && *req.AI != "" {
+		if _, ok := enemyStrategies[*req.AI]; !ok {
+			return &errorMessage{Error: "unknown ai"}
+		}
+	}
+	t, err := h.svc.GetTyrant(req.TyrantID)
 	if err != nil {
-		// notify only the sender
-		_ = c.conn.WriteJSON(map[string]any{"error": "tyrant not found"})
-		return
+		return &errorMessage{Error: "tyrant not found"}
 	}
 	en := false
-	if enemy != nil {
-		en = *enemy
+	if req.Enemy != nil {
+		en = *req.Enemy
+	}
+	lvl := DefaultLevel
+	if req.Level != nil && *req.Level > 0 {
+		lvl = *req.Level
 	}
 	h.mu.Lock()
-	if _, exists := h.participants[t.ID]; !exists {
-		p := &Participant{
+	// an explicit instance, or the caller's own combatant of this species,
+	// is taken over; anything else joins as a new combatant
+	var p *Participant
+	switch {
+	case req.Instance != nil && *req.Instance != "":
+		p = h.participants[*req.Instance]
+		if p == nil || p.Tyrant.ID != t.ID {
+			h.mu.Unlock()
+			return &errorMessage{Error: "instance not found"}
+		}
+	case req.User != nil && *req.User != "":
+		if ids := h.ownedInstancesLocked(t.ID, *req.User, en); len(ids) > 0 {
+			p = h.participants[ids[0]]
+		}
+	}
+	if p == nil {
+		id := h.nextInstanceIDLocked(t.ID)
+		p = &Participant{
+			ID:        id,
+			Name:      defaultInstanceName(t, id),
 			Tyrant:    t,
 			Enemy:     en,
+			Level:     lvl,
 			FullHP:    t.HP,
 			CurrentHP: t.HP,
 			Alive:     true,
@@ -396,33 +577,102 @@ func (h *Hub) handleJoin(c *Client, tyrantID string, enemy *bool) {
 				Current int
 			}),
 		}
+		if req.Name != nil && *req.Name != "" {
+			p.Name = *req.Name
+		}
 		for _, atk := range t.Attacks {
 			p.AttackPP[atk.Name] = &struct {
 				Full    int
 				Current int
 			}{Full: atk.PP, Current: atk.PP}
 		}
-		h.participants[t.ID] = p
+		h.participants[id] = p
+		h.logEventLocked("join", map[string]any{"id": id, "tyrant": t.ID, "name": p.Name, "enemy": en, "level": lvl, "fullHp": p.FullHP})
+	}
+	id := p.ID
+	if req.User != nil && *req.User != "" {
+		p.Owner = *req.User
+	}
+	if req.AI != nil {
+		// an empty ai hands the participant back to its client
+		p.AI = *req.AI
+	}
+	h.tyrantIDToClient[id] = c
+	token := h.issueSessionLocked(id)
+	if h.inBattle && h.currentActor == id {
+		// control changed hands mid-turn
+		h.armTurnTimerLocked()
 	}
-	h.tyrantIDToClient[t.ID] = c
 	// recompute turn order and build current queue
 	h.computeTurnOrderLocked()
 	turns := h.turnsViewLocked()
 	h.mu.Unlock()
 
 	// broadcast join event with full queue to everyone
-	h.broadcast(map[string]any{"joined": t.ID, "enemy": en, "turns": turns})
+	h.broadcast(joinedMessage{Joined: id, Tyrant: t.ID, Name: p.Name, Enemy: p.Enemy, AI: p.AI, Turns: turns})
+	// only the joining client learns the token to resume with
+	_ = c.writeMessage(sessionMessage{Session: sessionInfo{ID: id, Token: token}})
+	return nil
 }
 
-func (h *Hub) handleBattle(startWith string, voteEnabled bool) {
+// battleSetup holds the options of a battle message.
+type battleSetup struct {
+	StartWith   string
+	VoteEnabled bool
+	Seed        int64
+	Rules       string
+	// TurnTimeout is in seconds; 0 disables the turn timer
+	TurnTimeout int
+	// AIDelay is in milliseconds; 0 uses DefaultAIDelay
+	AIDelay int
+}
+
+func (h *Hub) handleBattle(setup battleSetup) *errorMessage {
+	calc, ok := damageCalculator(setup.Rules)
+	if !ok {
+		return &errorMessage{Error: "unknown rules"}
+	}
+	if setup.TurnTimeout < 0 {
+		return &errorMessage{Error: "invalid turnTimeout"}
+	}
+	if setup.AIDelay < 0 {
+		return &errorMessage{Error: "invalid aiDelay"}
+	}
+	entries, err := h.svc.ListTypeChart()
+	if err != nil {
+		log.Printf("scene: load type chart: %v", err)
+	}
+	startWith, voteEnabled, seed := setup.StartWith, setup.VoteEnabled, setup.Seed
 	h.mu.Lock()
+	// allies lost in an unfinished UNTIL_DEATH battle do not come back
+	for id, p := range h.participants {
+		if h.lostLocked(p) {
+			delete(h.participants, id)
+			delete(h.tyrantIDToClient, id)
+			h.revokeSessionLocked(id)
+		}
+	}
+	h.typeChart = newTypeChart(entries)
+	h.rng = newBattleRand(h.newRand, seed)
+	h.rules = setup.Rules
+	h.damage = calc
+	h.mode = ""
+	h.turnTimeout = time.Duration(setup.TurnTimeout) * time.Second
+	h.aiDelay = DefaultAIDelay
+	if setup.AIDelay > 0 {
+		h.aiDelay = time.Duration(setup.AIDelay) * time.Millisecond
+	}
 	h.inBattle = !voteEnabled
 	h.votingActive = voteEnabled
 	h.battleStartedWith = startWith
-	// Reset HP/Alive and PP for a new battle
+	// Reset HP/Alive, PP, status and XP contribution for a new battle
 	for _, p := range h.participants {
 		p.CurrentHP = p.FullHP
 		p.Alive = p.FullHP > 0
+		p.Statuses = nil
+		p.DamageDealt = 0
+		p.Defending = false
+		p.Buffs = nil
 		for _, v := range p.AttackPP {
 			if v != nil {
 				v.Current = v.Full
@@ -430,6 +680,7 @@ func (h *Hub) handleBattle(startWith string, voteEnabled bool) {
 		}
 	}
 	h.computeTurnOrderLocked()
+	h.startBattleLogLocked(startWith, voteEnabled)
 	// align start index to provided tyrant if exists
 	h.turnIndex = 0
 	for i, id := range h.turnOrder {
@@ -440,6 +691,7 @@ func (h *Hub) handleBattle(startWith string, voteEnabled bool) {
 	}
 	next := h.nextAliveLocked()
 	h.currentActor = next
+	h.armTurnTimerLocked()
 	if voteEnabled {
 		h.voteUntilDeath = 0
 		h.voteToParty = 0
@@ -451,31 +703,21 @@ func (h *Hub) handleBattle(startWith string, voteEnabled bool) {
 			}
 		}
 		h.mu.Unlock()
-		h.broadcast(map[string]any{"voting": map[string]int{"UNTIL_DEATH": 0, "TO_PARTY": 0}})
-		return
+		h.broadcast(votingMessage{Voting: map[string]int{"UNTIL_DEATH": 0, "TO_PARTY": 0}, Seed: &seed, Rules: setup.Rules, TurnTimeout: &setup.TurnTimeout})
+		return nil
 	}
-	// build tyrants snapshot (only alive participants)
-	tyrantUpdates := make([]map[string]any, 0, len(h.participants))
-	for id, p := range h.participants {
-		if p == nil || !p.Alive {
-			continue
-		}
-		attacksArr := make([]map[string]any, 0, len(p.AttackPP))
-		for name, v := range p.AttackPP {
-			attacksArr = append(attacksArr, map[string]any{"name": name, "fullPP": v.Full, "currentPP": v.Current})
-		}
-		tyrantUpdates = append(tyrantUpdates, map[string]any{
-			"id":        id,
-			"fullHp":    p.FullHP,
-			"currentHp": p.CurrentHP,
-			"asset":     p.Tyrant.Asset,
-			"enemy":     p.Enemy,
-			"attacks":   attacksArr,
-		})
+	payload := battleMessage{
+		Battle:       startWith,
+		Turns:        h.turnsViewLocked(),
+		Tyrants:      h.tyrantsSnapshotLocked(),
+		Seed:         seed,
+		Rules:        setup.Rules,
+		TurnTimeout:  setup.TurnTimeout,
+		TurnDeadline: h.turnDeadlineLocked(),
 	}
 	h.mu.Unlock()
-	turns := h.turnsViewLocked()
-	h.broadcast(map[string]any{"battle": startWith, "turns": turns, "tyrants": tyrantUpdates})
+	h.broadcast(payload)
+	return nil
 }
 
 func (h *Hub) computeTurnOrderLocked() {
@@ -483,8 +725,14 @@ func (h *Hub) computeTurnOrderLocked() {
 	for id := range h.participants {
 		order = append(order, id)
 	}
-	sort.Slice(order, func(i, j int) bool {
-		return h.participants[order[i]].Tyrant.Speed > h.participants[order[j]].Tyrant.Speed
+	// ties go to the lower id (and instance number), so a seeded battle always
+	// plays in the same order
+	sort.SliceStable(order, func(i, j int) bool {
+		si, sj := h.participants[order[i]].Tyrant.Speed, h.participants[order[j]].Tyrant.Speed
+		if si != sj {
+			return si > sj
+		}
+		return lessInstance(order[i], order[j])
 	})
 	h.turnOrder = order
 	if h.turnIndex >= len(h.turnOrder) {
@@ -508,39 +756,22 @@ func (h *Hub) nextAliveLocked() string {
 	return ""
 }
 
-func (h *Hub) handleAttack(a attackEvent) {
+func (h *Hub) handleAttack(a attackEvent) *errorMessage {
 	h.mu.Lock()
 	if !h.inBattle {
 		// not in battle
-		c := h.tyrantIDToClient[a.User]
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "not in battle"})
-		}
-		return
+		return &errorMessage{Error: "not in battle"}
 	}
 	attacker := h.participants[a.User]
-	target := h.participants[a.Target]
-	if attacker == nil || target == nil || !attacker.Alive || !target.Alive {
-		c := h.tyrantIDToClient[a.User]
+	if attacker == nil || !attacker.Alive {
 		h.mu.Unlock()
-		if c != nil {
-			msg := "invalid attacker or target"
-			if target == nil {
-				msg = "target not found"
-			}
-			_ = c.conn.WriteJSON(map[string]any{"error": msg})
-		}
-		return
+		return &errorMessage{Error: "invalid attacker or target"}
 	}
 	// enforce turn: only current actor can act
 	if h.currentActor != "" && h.currentActor != a.User {
-		c := h.tyrantIDToClient[a.User]
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "not your turn", "expected": h.currentActor})
-		}
-		return
+		return &errorMessage{Error: "not your turn", Expected: h.currentActor}
 	}
 	// Basic validation: attack must exist by name on attacker's tyrant
 	var atkDef *models.Attack
@@ -551,65 +782,189 @@ func (h *Hub) handleAttack(a attackEvent) {
 		}
 	}
 	if atkDef == nil {
-		c := h.tyrantIDToClient[a.User]
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "unknown attack"})
-		}
-		return
+		return &errorMessage{Error: "unknown attack"}
 	}
-	// Check and consume PP
+	// Check PP before picking targets, so a random pick is never rolled for nothing
 	pp := attacker.AttackPP[a.Attack]
 	if pp == nil || pp.Current <= 0 {
-		c := h.tyrantIDToClient[a.User]
+		// with nothing left to use, the only way forward is an action
+		struggle := len(usableAttacks(attacker)) == 0
 		h.mu.Unlock()
-		if c != nil {
-			_ = c.conn.WriteJSON(map[string]any{"error": "no PP left for attack"})
-		}
-		return
+		return &errorMessage{Error: "no PP left for attack", Struggle: struggle}
+	}
+	// the move decides who it may target (revives need a fainted one) and how many
+	move := parseMove(atkDef)
+	requested := a.Targets
+	if len(requested) == 0 && a.Target != "" {
+		requested = []string{a.Target}
+	}
+	targetIDs, errMsg := h.resolveTargetsLocked(a.User, requested, move)
+	if errMsg != "" {
+		h.mu.Unlock()
+		return &errorMessage{Error: errMsg, Category: move.category, Targets: move.target, Area: move.area}
 	}
 	pp.Current--
-	// Damage calculation
-	random := rand.Intn(100) + 1 // 1..100
-	atkStat := attacker.Tyrant.Attack
-	defStat := target.Tyrant.Defense
-	power := atkDef.Power
-	damage := (atkStat*(random+(power*10)) - defStat) / 200
-	if damage < 1 {
-		damage = 1
-	}
-	if random >= 90 {
-		damage = damage * 2
-	}
-	target.CurrentHP -= damage
-	if target.CurrentHP <= 0 {
-		target.CurrentHP = 0
-		target.Alive = false
-	}
-	// Build HP+PP update snapshot as array
-	tyrantUpdates := make([]map[string]any, 0, len(h.participants))
-	for id, p := range h.participants {
-		if p == nil || !p.Alive {
-			continue
+	lastAttack, logData, events := h.strikeLocked(a.User, targetIDs, attacker, atkDef)
+	logData["statusEvents"] = events
+	h.logEventLocked("attack", logData)
+	// victory is checked once, after every hit landed
+	status, events := h.endTurnLocked(a.User, events)
+	if status == nil {
+		state := h.stateLocked(events)
+		state.LastAttack = &lastAttack
+		status = &state
+	}
+	payload := h.updateLocked(*status)
+	h.mu.Unlock()
+
+	h.broadcast(payload)
+	return nil
+}
+
+// strikeLocked resolves atkDef from attacker on every target, including a
+// confused attacker hurting itself instead, and returns the lastAttack view,
+// the log data and the status events it caused. For single target moves the
+// result sits in lastAttack itself; area moves list one result per target in
+// lastAttack.targets.
+func (h *Hub) strikeLocked(userID string, targetIDs []string, attacker *Participant, atkDef *models.Attack) (attackResult, map[string]any, []statusEvent) {
+	lastAttack := attackResult{User: userID, Attack: atkDef.Name}
+	logData := map[string]any{"user": userID, "attack": atkDef.Name}
+	move := parseMove(atkDef)
+	single := move.area == AreaSingle
+	if single {
+		lastAttack.Target = targetIDs[0]
+		logData["target"] = targetIDs[0]
+	}
+	var events []statusEvent
+	if attacker.hasStatus(statusConfusion) && h.rng.Intn(100) < confusionSelfChance {
+		// confused: the attacker hurts itself instead of the targets
+		dmg := h.confusionSelfHitLocked(attacker)
+		lastAttack.Confused = true
+		events = append(events, statusEvent{ID: userID, Status: statusConfusion, Event: "selfHit", Damage: dmg})
+		logData["confused"] = true
+		logData["damage"] = dmg
+		if !single {
+			logData["targets"] = targetIDs
 		}
-		attacksArr := make([]map[string]any, 0, len(p.AttackPP))
-		for name, v := range p.AttackPP {
-			attacksArr = append(attacksArr, map[string]any{"name": name, "fullPP": v.Full, "currentPP": v.Current})
-		}
-		tyrantUpdates = append(tyrantUpdates, map[string]any{
-			"id":        id,
-			"fullHp":    p.FullHP,
-			"currentHp": p.CurrentHP,
-			"asset":     p.Tyrant.Asset,
-			"enemy":     p.Enemy,
-			"attacks":   attacksArr,
-		})
+		return lastAttack, logData, events
+	}
+	if single {
+		events = h.hitLocked(userID, targetIDs[0], attacker, atkDef, move, false, &lastAttack, logData)
+		return lastAttack, logData, events
+	}
+	if move.category != MoveDamage {
+		lastAttack.Category = move.category
+		logData["category"] = move.category
+	}
+	results := make([]attackResult, 0, len(targetIDs))
+	logs := make([]map[string]any, 0, len(targetIDs))
+	for _, id := range targetIDs {
+		view := attackResult{Target: id}
+		entry := map[string]any{"target": id}
+		events = append(events, h.hitLocked(userID, id, attacker, atkDef, move, len(targetIDs) > 1, &view, entry)...)
+		results = append(results, view)
+		logs = append(logs, entry)
+	}
+	lastAttack.Targets = results
+	logData["targets"] = logs
+	return lastAttack, logData, events
+}
+
+// hitLocked applies the move to one target, writing the result to view and
+// the log entry. Damaging moves first roll to hit and may miss. spread marks
+// a hit shared among several targets, which deals less damage. Support moves
+// heal, buff or revive instead.
+func (h *Hub) hitLocked(userID, targetID string, attacker *Participant, atkDef *models.Attack, move moveDef, spread bool, view *attackResult, entry map[string]any) []statusEvent {
+	target := h.participants[targetID]
+	if move.category != MoveDamage {
+		h.applySupportLocked(move, target, view, entry)
+		return nil
+	}
+	var events []statusEvent
+	hit := h.rollHitLocked(attacker, target, atkDef)
+	view.Hit = &hit
+	entry["hit"] = hit
+	if !hit.Hit {
+		view.Missed = true
+		entry["missed"] = true
+		return nil
+	}
+	// Elemental effectiveness from the attack attributes vs the defender types
+	effectiveness := h.typeChart.multiplier(atkDef.Attributes, target.Tyrant.Types)
+	res := h.damage.Calculate(DamageInput{
+		Attacker:      attacker,
+		Defender:      target,
+		Attack:        atkDef,
+		Effectiveness: effectiveness,
+		Rand:          h.rng,
+	})
+	// spread, stances and stat stages apply under every rule set
+	extra := buffModifiers(attacker, target)
+	if target.Defending {
+		extra = append(extra, DamageModifier{Name: "defend", Factor: 0.5})
+	}
+	if spread {
+		extra = append(extra, DamageModifier{Name: "spread", Factor: spreadFactor})
+	}
+	if len(extra) > 0 {
+		res.Modifiers = append(res.Modifiers, extra...)
+		res.Damage = applyModifiers(res.Base, res.Modifiers, effectiveness)
+	}
+	damage := res.Damage
+	// a damaging hit wakes a sleeping target
+	if damage > 0 && target.hasStatus(statusSleep) {
+		delete(target.Statuses, statusSleep)
+		events = append(events, statusEvent{ID: targetID, Status: statusSleep, Event: "cured"})
+	}
+	hpBefore := target.CurrentHP
+	h.damageLocked(target, damage)
+	if target.Enemy != attacker.Enemy {
+		attacker.DamageDealt += hpBefore - target.CurrentHP
+	}
+	view.Effectiveness = &effectiveness
+	view.Damage = &res
+	view.Effect = effectivenessLabel(effectiveness)
+	events = append(events, h.inflictStatusesLocked(targetID, target, atkDef)...)
+	entry["roll"] = res.Roll
+	entry["base"] = res.Base
+	entry["modifiers"] = res.Modifiers
+	entry["damage"] = damage
+	entry["crit"] = res.Crit
+	entry["effectiveness"] = effectiveness
+	return events
+}
+
+// stateLocked builds the updateState payload for a battle still in progress.
+func (h *Hub) stateLocked(events []statusEvent) stateUpdate {
+	return stateUpdate{Tyrants: h.tyrantsSnapshotLocked(), StatusEvents: events, Mode: h.mode}
+}
+
+// updateLocked builds the update broadcast after a turn changed the state.
+func (h *Hub) updateLocked(status stateUpdate) updateMessage {
+	return updateMessage{UpdateState: status, Turns: h.turnsViewLocked(), TurnDeadline: h.turnDeadlineLocked()}
+}
+
+// battleStartPayloadLocked builds the message that opens the turns after a vote.
+func (h *Hub) battleStartPayloadLocked(counts map[string]int) battleMessage {
+	return battleMessage{
+		Battle:       h.battleStartedWith,
+		Turns:        h.turnsViewLocked(),
+		Voting:       counts,
+		Tyrants:      h.tyrantsSnapshotLocked(),
+		Seed:         h.rng.seed,
+		Rules:        h.rules,
+		Mode:         h.mode,
+		TurnTimeout:  int(h.turnTimeout / time.Second),
+		TurnDeadline: h.turnDeadlineLocked(),
 	}
-	// Last attack used
-	lastAttack := map[string]any{"user": a.User, "target": a.Target, "attack": a.Attack}
-	// Determine victory
+}
+
+// outcomeLocked returns "WIN" or "DEFEAT" once one side is fully down.
+func (h *Hub) outcomeLocked() string {
 	allEnemiesDown := true
 	allAlliesDown := true
+	anyAllyDown := false
 	for _, p := range h.participants {
 		if p.Enemy && p.Alive {
 			allEnemiesDown = false
@@ -617,59 +972,162 @@ func (h *Hub) handleAttack(a attackEvent) {
 		if !p.Enemy && p.Alive {
 			allAlliesDown = false
 		}
+		if !p.Enemy && !p.Alive {
+			anyAllyDown = true
+		}
 	}
-	var status any = map[string]any{"tyrants": tyrantUpdates, "lastAttack": lastAttack}
-	if allEnemiesDown {
-		status = "WIN"
-		h.inBattle = false
-		// remove only enemies; keep protagonists for future battles
-		for id, p := range h.participants {
-			if p.Enemy {
-				delete(h.participants, id)
-				delete(h.tyrantIDToClient, id)
-			}
+	switch {
+	case allEnemiesDown:
+		return "WIN"
+	case allAlliesDown:
+		return "DEFEAT"
+	case h.mode == ModeToParty && anyAllyDown:
+		return "DEFEAT"
+	}
+	return ""
+}
+
+// endTurnLocked closes actorID's turn: timed conditions count down, victory is
+// checked, and otherwise the turn passes on. When the battle ends, the final
+// updateState value is returned; it is nil while the battle goes on.
+func (h *Hub) endTurnLocked(actorID string, events []statusEvent) (*stateUpdate, []statusEvent) {
+	events = append(events, h.expireStatusesLocked(actorID)...)
+	outcome := h.outcomeLocked()
+	if outcome == "" {
+		events = append(events, h.advanceTurnLocked()...)
+		outcome = h.outcomeLocked()
+	}
+	if outcome != "" {
+		result := h.finishBattleLocked(outcome)
+		return &result, events
+	}
+	return nil, events
+}
+
+// advanceTurnLocked hands the turn to the next alive combatant, running
+// start-of-turn status ticks and skipping those who lose their turn.
+func (h *Hub) advanceTurnLocked() []statusEvent {
+	defer h.armTurnTimerLocked()
+	var events []statusEvent
+	h.currentActor = ""
+	last := ""
+	// bounded: every skip counts down a condition, so someone acts eventually
+	for i := 0; i < 4*len(h.turnOrder); i++ {
+		id := h.nextAliveLocked()
+		if id == "" {
+			return events
 		}
-		h.computeTurnOrderLocked()
-	} else if allAlliesDown {
-		status = "DEFEAT"
-		h.inBattle = false
-		// remove only enemies; keep protagonists for future battles
-		for id, p := range h.participants {
-			if p.Enemy {
-				delete(h.participants, id)
-				delete(h.tyrantIDToClient, id)
-			}
+		last = id
+		p := h.participants[id]
+		// a defensive stance lasts until the defender's next turn
+		p.Defending = false
+		skip, tickEvents := h.tickStatusesLocked(id, p)
+		events = append(events, tickEvents...)
+		if h.outcomeLocked() != "" {
+			return events
 		}
-		h.computeTurnOrderLocked()
-	}
-	// Next turn
-	if h.inBattle {
-		next := h.nextAliveLocked()
-		h.currentActor = next
-	} else {
-		h.currentActor = ""
+		if !skip && p.Alive {
+			h.currentActor = id
+			return events
+		}
+		events = append(events, h.expireStatusesLocked(id)...)
 	}
-	turns := h.turnsViewLocked()
-	h.mu.Unlock()
+	h.currentActor = last
+	return events
+}
 
-	h.broadcast(map[string]any{"updateState": status, "turns": turns})
+// finishBattleLocked stops the battle, awards XP on a win, closes its log and
+// removes only enemies; protagonists stay for future battles. It returns the
+// final updateState value: the outcome, with the XP breakdown on a win.
+func (h *Hub) finishBattleLocked(outcome string) stateUpdate {
+	result := stateUpdate{Outcome: outcome, Mode: h.mode}
+	if outcome == "WIN" {
+		result.XPAwarded = h.awardXPLocked()
+	}
+	h.endBattleLogLocked(outcome)
+	h.inBattle = false
+	h.currentActor = ""
+	h.stopTurnTimerLocked()
+	for id, p := range h.participants {
+		// enemies leave after the battle, and so do allies lost UNTIL_DEATH
+		if p.Enemy || h.lostLocked(p) {
+			delete(h.participants, id)
+			delete(h.tyrantIDToClient, id)
+			h.revokeSessionLocked(id)
+		} else {
+			p.Statuses = nil
+			p.Defending = false
+			p.Buffs = nil
+		}
+	}
+	h.mode = ""
+	h.computeTurnOrderLocked()
+	return result
 }
 
-func (h *Hub) broadcast(v any) {
+// broadcast queues m for every client in the room, encoded once per
+// protocol version. It never waits on a socket; clients that cannot keep up
+// are disconnected by their queue.
+func (h *Hub) broadcast(m serverMessage) {
 	h.mu.RLock()
-	conns := make([]*websocket.Conn, 0, len(h.clients))
+	clients := make([]*Client, 0, len(h.clients))
 	for c := range h.clients {
-		conns = append(conns, c.conn)
+		clients = append(clients, c)
 	}
 	h.mu.RUnlock()
-	for _, conn := range conns {
-		_ = conn.WriteJSON(v)
+	encoded := make(map[int][]byte)
+	for _, c := range clients {
+		data, ok := encoded[c.version]
+		if !ok {
+			var err error
+			if data, err = encodeMessage(m, c.version); err != nil {
+				log.Printf("scene: broadcast: %v", err)
+				return
+			}
+			encoded[c.version] = data
+		}
+		_ = c.enqueue(data)
+	}
+}
+
+// tyrantsSnapshotLocked returns the HP/PP/status view of every participant,
+// fainted ones included so clients can still target them with revives.
+func (h *Hub) tyrantsSnapshotLocked() []tyrantView {
+	tyrantUpdates := make([]tyrantView, 0, len(h.participants))
+	for id, p := range h.participants {
+		tyrantUpdates = append(tyrantUpdates, p.view(id))
+	}
+	return tyrantUpdates
+}
+
+// view is the snapshot of the participant sent to clients.
+func (p *Participant) view(id string) tyrantView {
+	attacks := make([]attackPPView, 0, len(p.AttackPP))
+	for _, atk := range p.Tyrant.Attacks {
+		if v := p.AttackPP[atk.Name]; v != nil {
+			attacks = append(attacks, attackPPView{Name: atk.Name, FullPP: v.Full, CurrentPP: v.Current})
+		}
+	}
+	return tyrantView{
+		ID:        id,
+		Tyrant:    p.Tyrant.ID,
+		Name:      p.displayName(),
+		FullHP:    p.FullHP,
+		CurrentHP: p.CurrentHP,
+		Asset:     p.Tyrant.Asset,
+		Enemy:     p.Enemy,
+		Alive:     p.Alive,
+		Level:     p.level(),
+		Attacks:   attacks,
+		Status:    p.statusViews(),
+		Defending: p.Defending,
+		Buffs:     p.buffView(),
 	}
 }
 
 // turnsViewLocked returns the ordered list of upcoming turns starting from currentActor.
-func (h *Hub) turnsViewLocked() []map[string]any {
-	result := make([]map[string]any, 0, len(h.turnOrder))
+func (h *Hub) turnsViewLocked() []turnView {
+	result := make([]turnView, 0, len(h.turnOrder))
 	if len(h.turnOrder) == 0 {
 		return result
 	}
@@ -692,10 +1150,12 @@ func (h *Hub) turnsViewLocked() []map[string]any {
 		if p == nil || !p.Alive {
 			continue
 		}
-		result = append(result, map[string]any{
-			"id":    id,
-			"asset": p.Tyrant.Asset,
-			"enemy": p.Enemy,
+		result = append(result, turnView{
+			ID:     id,
+			Tyrant: p.Tyrant.ID,
+			Name:   p.displayName(),
+			Asset:  p.Tyrant.Asset,
+			Enemy:  p.Enemy,
 		})
 	}
 	return result
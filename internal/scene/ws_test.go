@@ -0,0 +1,76 @@
+package scene
+
+import "testing"
+
+// newTestHub seats the given joins at a hub backed by the replay roster.
+func newTestHub(t *testing.T, joins ...joinRequest) *Hub {
+	t.Helper()
+	h := NewHub(&stubService{tyrants: replayRoster()}, NewMathRand)
+	c := &Client{send: make(chan []byte, sendQueueSize), done: make(chan struct{})}
+	for _, req := range joins {
+		if failure := h.handleJoin(c, req); failure != nil {
+			t.Fatalf("join %s: %s", req.TyrantID, failure.Error)
+		}
+		<-c.send
+	}
+	return h
+}
+
+func TestLeaveDuringVotePassesTheTurn(t *testing.T) {
+	enemy := true
+	h := newTestHub(t,
+		joinRequest{TyrantID: "golem", Enemy: &enemy},
+		joinRequest{TyrantID: "tumba"},
+		joinRequest{TyrantID: "tumba"},
+	)
+	if failure := h.handleBattle(battleSetup{StartWith: "tumba", VoteEnabled: true, Seed: 1}); failure != nil {
+		t.Fatalf("battle: %s", failure.Error)
+	}
+	if failure := h.handleVote("tumba#2", "TO_PARTY"); failure != nil {
+		t.Fatalf("vote: %s", failure.Error)
+	}
+	// the pending actor leaves and its leave completes the vote
+	if failure := h.handleLeave("tumba"); failure != nil {
+		t.Fatalf("leave: %s", failure.Error)
+	}
+
+	h.mu.RLock()
+	inBattle, actor, order := h.inBattle, h.currentActor, h.turnOrder
+	h.mu.RUnlock()
+	if !inBattle {
+		t.Fatal("battle did not start after the last vote")
+	}
+	if actor != "tumba#2" {
+		t.Fatalf("currentActor = %q, want tumba#2", actor)
+	}
+	for _, id := range order {
+		if id == "tumba" {
+			t.Fatalf("turn order %v still has the leaver", order)
+		}
+	}
+	if failure := h.handleAttack(attackEvent{User: "tumba#2", Target: "golem", Attack: "bite"}); failure != nil {
+		t.Fatalf("attack after leave: %s", failure.Error)
+	}
+}
+
+func TestStateUpdateListsFainted(t *testing.T) {
+	enemy := true
+	h := newTestHub(t,
+		joinRequest{TyrantID: "golem", Enemy: &enemy},
+		joinRequest{TyrantID: "tumba"},
+	)
+	h.mu.Lock()
+	h.damageLocked(h.participants["tumba"], 1000)
+	update := h.stateLocked(nil)
+	h.mu.Unlock()
+
+	for _, v := range update.Tyrants {
+		if v.ID == "tumba" {
+			if v.Alive || v.CurrentHP != 0 {
+				t.Fatalf("fainted tumba = %+v, want alive false and 0 HP", v)
+			}
+			return
+		}
+	}
+	t.Fatalf("fainted tumba missing from %+v", update.Tyrants)
+}
@@ -0,0 +1,69 @@
+package scene
+
+import (
+	"sort"
+)
+
+// xpAward is one ally's share of the XP won in a battle. Half of the pool is
+// split evenly among the allies at the table (Participation) and half in
+// proportion to the damage each dealt to enemies (Contribution).
+type xpAward struct {
+	ID            string `json:"id"`
+	User          string `json:"user,omitempty"`
+	DamageDealt   int    `json:"damageDealt"`
+	Participation int    `json:"participation"`
+	Contribution  int    `json:"contribution"`
+	XP            int    `json:"xp"`
+}
+
+// enemyXP is what defeating an enemy is worth: its average stat times its level.
+func enemyXP(p *Participant) int {
+	t := p.Tyrant
+	return (t.HP + t.Attack + t.Defense + t.Speed) / 4 * p.level()
+}
+
+// awardXPLocked splits the XP of the defeated enemies among the allies and
+// queues the owners' XP update.
+func (h *Hub) awardXPLocked() []xpAward {
+	pool := 0
+	var allies []string
+	totalDamage := 0
+	for id, p := range h.participants {
+		if p.Enemy {
+			pool += enemyXP(p)
+			continue
+		}
+		if h.lostLocked(p) {
+			continue
+		}
+		allies = append(allies, id)
+		totalDamage += p.DamageDealt
+	}
+	sort.Strings(allies)
+	awards := make([]xpAward, 0, len(allies))
+	if len(allies) == 0 {
+		return awards
+	}
+	even := pool / 2 / len(allies)
+	byDamage := pool - pool/2
+	perUser := make(map[string]int)
+	for _, id := range allies {
+		p := h.participants[id]
+		a := xpAward{ID: id, User: p.Owner, DamageDealt: p.DamageDealt, Participation: even}
+		if totalDamage > 0 {
+			a.Contribution = byDamage * p.DamageDealt / totalDamage
+		} else {
+			a.Contribution = byDamage / len(allies)
+		}
+		a.XP = a.Participation + a.Contribution
+		if a.User != "" {
+			perUser[a.User] += a.XP
+		}
+		awards = append(awards, a)
+	}
+	h.logEventLocked("xp", map[string]any{"pool": pool, "xpAwarded": awards})
+	if len(perUser) > 0 {
+		h.userOps = append(h.userOps, func() error { return h.svc.AwardXP(perUser) })
+	}
+	return awards
+}
@@ -0,0 +1,143 @@
+package typechart
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+
+    "github.com/matheustorresii/tyrants-back/internal/auth"
+    "github.com/matheustorresii/tyrants-back/internal/db"
+    "github.com/matheustorresii/tyrants-back/internal/models"
+)
+
+// Service defines the behaviors the handler requires from the persistence layer.
+type Service interface {
+    auth.UserLookup
+    ListTypeChart() ([]models.TypeEffectiveness, error)
+    ReplaceTypeChart(entries []models.TypeEffectiveness) error
+    SetTypeEffectiveness(e models.TypeEffectiveness) error
+    DeleteTypeEffectiveness(attribute, defenderType string) error
+}
+
+// Handler provides HTTP handlers for the elemental type chart.
+type Handler struct {
+    svc Service
+}
+
+// NewHandler creates a new type chart Handler.
+func NewHandler(svc Service) *Handler {
+    return &Handler{svc: svc}
+}
+
+type multiplierRequest struct {
+    Multiplier *float64 `json:"multiplier"`
+}
+
+// ChartCollection handles /types/chart for GET (list) and PUT (replace, admin only)
+func (h *Handler) ChartCollection(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodGet:
+        items, err := h.svc.ListTypeChart()
+        if err != nil {
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(items)
+        return
+
+    case http.MethodPut:
+        if !auth.RequireAdmin(w, r, h.svc) {
+            return
+        }
+        var req []models.TypeEffectiveness
+        dec := json.NewDecoder(r.Body)
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(&req); err != nil {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        for _, e := range req {
+            if e.Attribute == "" || e.DefenderType == "" || e.Multiplier < 0 {
+                http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+                return
+            }
+        }
+        if err := h.svc.ReplaceTypeChart(req); err != nil {
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        items, err := h.svc.ListTypeChart()
+        if err != nil {
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(items)
+        return
+
+    default:
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
+}
+
+// ChartItem handles /types/chart/{attribute}/{defenderType} for PUT and DELETE (admin only)
+func (h *Handler) ChartItem(w http.ResponseWriter, r *http.Request) {
+    if !strings.HasPrefix(r.URL.Path, "/types/chart/") {
+        http.NotFound(w, r)
+        return
+    }
+    parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/types/chart/"), "/")
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        http.NotFound(w, r)
+        return
+    }
+    attribute, defenderType := parts[0], parts[1]
+
+    switch r.Method {
+    case http.MethodPut:
+        if !auth.RequireAdmin(w, r, h.svc) {
+            return
+        }
+        var req multiplierRequest
+        dec := json.NewDecoder(r.Body)
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(&req); err != nil {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        if req.Multiplier == nil || *req.Multiplier < 0 {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        item := models.TypeEffectiveness{Attribute: attribute, DefenderType: defenderType, Multiplier: *req.Multiplier}
+        if err := h.svc.SetTypeEffectiveness(item); err != nil {
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(item)
+        return
+
+    case http.MethodDelete:
+        if !auth.RequireAdmin(w, r, h.svc) {
+            return
+        }
+        if err := h.svc.DeleteTypeEffectiveness(attribute, defenderType); err != nil {
+            if errors.Is(err, db.ErrTypeEffectivenessNotFound) {
+                http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+                return
+            }
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+        return
+
+    default:
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
+}
@@ -6,17 +6,23 @@ import (
     "net/http"
     "strings"
 
+    "github.com/matheustorresii/tyrants-back/internal/auth"
     "github.com/matheustorresii/tyrants-back/internal/db"
     "github.com/matheustorresii/tyrants-back/internal/models"
 )
 
 // Service defines the behaviors the handler requires from the persistence layer.
 type Service interface {
+    auth.UserLookup
     CreateTyrant(t models.Tyrant) error
     GetTyrant(id string) (models.Tyrant, error)
     ListTyrants() ([]models.Tyrant, error)
     UpdateTyrant(id string, t models.Tyrant) (models.Tyrant, error)
     DeleteTyrant(id string) error
+    ListEvolutionRules(from string) ([]models.EvolutionRule, error)
+    GetEvolutionRule(from, to string) (models.EvolutionRule, error)
+    SetEvolutionRule(r models.EvolutionRule) error
+    DeleteEvolutionRule(from, to string) error
 }
 
 // Handler provides HTTP handlers for tyrant flows.
@@ -33,13 +39,34 @@ type attackPayload struct {
     Name       string   `json:"name"`
     Power      int      `json:"power"`
     PP         int      `json:"pp"`
+    Accuracy   *int     `json:"accuracy,omitempty"`
     Attributes []string `json:"attributes"`
 }
 
+// toModel maps the payload to an attack, defaulting accuracy to
+// models.DefaultAccuracy. It reports false when accuracy is out of 1..100.
+func (a attackPayload) toModel() (models.Attack, bool) {
+    accuracy := models.DefaultAccuracy
+    if a.Accuracy != nil {
+        accuracy = *a.Accuracy
+    }
+    if accuracy < 1 || accuracy > 100 {
+        return models.Attack{}, false
+    }
+    return models.Attack{
+        Name:       a.Name,
+        Power:      a.Power,
+        PP:         a.PP,
+        Accuracy:   accuracy,
+        Attributes: a.Attributes,
+    }, true
+}
+
 type createTyrantRequest struct {
     ID         string           `json:"id"`
     Asset      string           `json:"asset"`
     Nickname   *string          `json:"nickname,omitempty"`
+    Types      *[]string        `json:"types,omitempty"`
     Evolutions *[]string        `json:"evolutions,omitempty"`
     Attacks    []attackPayload  `json:"attacks"`
     HP         int              `json:"hp"`
@@ -51,6 +78,7 @@ type createTyrantRequest struct {
 type updateTyrantRequest struct {
     Asset      string           `json:"asset"`
     Nickname   *string          `json:"nickname,omitempty"`
+    Types      *[]string        `json:"types,omitempty"`
     Evolutions *[]string        `json:"evolutions,omitempty"`
     Attacks    *[]attackPayload `json:"attacks,omitempty"`
     HP         int              `json:"hp"`
@@ -59,6 +87,12 @@ type updateTyrantRequest struct {
     Speed      int              `json:"speed"`
 }
 
+type evolutionRuleRequest struct {
+    MinLevel int     `json:"minLevel"`
+    MinXP    int     `json:"minXp"`
+    Item     *string `json:"item,omitempty"`
+}
+
 // TyrantsCollection handles /tyrants for GET (list) and POST (create)
 func (h *Handler) TyrantsCollection(w http.ResponseWriter, r *http.Request) {
     switch r.Method {
@@ -85,7 +119,11 @@ func (h *Handler) TyrantsCollection(w http.ResponseWriter, r *http.Request) {
             http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
             return
         }
-        // Build model; if evolutions omitted, keep empty slice (optional)
+        // Build model; if types/evolutions omitted, keep empty slice (optional)
+        var types []string
+        if req.Types != nil {
+            types = *req.Types
+        }
         var evolutions []string
         if req.Evolutions != nil {
             evolutions = *req.Evolutions
@@ -94,6 +132,7 @@ func (h *Handler) TyrantsCollection(w http.ResponseWriter, r *http.Request) {
             ID:         req.ID,
             Asset:      req.Asset,
             Nickname:   req.Nickname,
+            Types:      types,
             Evolutions: evolutions,
             HP:         req.HP,
             Attack:     req.Attack,
@@ -102,12 +141,12 @@ func (h *Handler) TyrantsCollection(w http.ResponseWriter, r *http.Request) {
         }
         // Map attacks (optional list; empty accepted)
         for _, a := range req.Attacks {
-            t.Attacks = append(t.Attacks, models.Attack{
-                Name:       a.Name,
-                Power:      a.Power,
-                PP:         a.PP,
-                Attributes: a.Attributes,
-            })
+            atk, ok := a.toModel()
+            if !ok {
+                http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+                return
+            }
+            t.Attacks = append(t.Attacks, atk)
         }
         if err := h.svc.CreateTyrant(t); err != nil {
             if errors.Is(err, db.ErrTyrantExists) {
@@ -134,6 +173,10 @@ func (h *Handler) TyrantsItem(w http.ResponseWriter, r *http.Request) {
         return
     }
     id := strings.TrimPrefix(r.URL.Path, "/tyrants/")
+    if from, rest, ok := strings.Cut(id, "/evolutions"); ok && from != "" && !strings.Contains(from, "/") && (rest == "" || strings.HasPrefix(rest, "/")) {
+        h.evolutions(w, r, from, strings.TrimPrefix(rest, "/"))
+        return
+    }
     if id == "" || strings.Contains(id, "/") {
         http.NotFound(w, r)
         return
@@ -183,8 +226,12 @@ func (h *Handler) TyrantsItem(w http.ResponseWriter, r *http.Request) {
             t.Nickname = current.Nickname
         }
         // mark optional collections as omitted by default
+        t.Types = nil
         t.Evolutions = nil
         t.Attacks = nil
+        if req.Types != nil {
+            t.Types = *req.Types
+        }
         if req.Evolutions != nil {
             t.Evolutions = *req.Evolutions
         }
@@ -194,12 +241,12 @@ func (h *Handler) TyrantsItem(w http.ResponseWriter, r *http.Request) {
         t.Speed = req.Speed
         if req.Attacks != nil {
             for _, a := range *req.Attacks {
-                t.Attacks = append(t.Attacks, models.Attack{
-                    Name:       a.Name,
-                    Power:      a.Power,
-                    PP:         a.PP,
-                    Attributes: a.Attributes,
-                })
+                atk, ok := a.toModel()
+                if !ok {
+                    http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+                    return
+                }
+                t.Attacks = append(t.Attacks, atk)
             }
         }
         item, err := h.svc.UpdateTyrant(id, t)
@@ -233,4 +280,93 @@ func (h *Handler) TyrantsItem(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// evolutions handles /tyrants/{id}/evolutions for GET (list) and
+// /tyrants/{id}/evolutions/{to} for GET, PUT and DELETE (admin only)
+func (h *Handler) evolutions(w http.ResponseWriter, r *http.Request, from, to string) {
+    if strings.Contains(to, "/") {
+        http.NotFound(w, r)
+        return
+    }
+    if to == "" {
+        if r.Method != http.MethodGet {
+            http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+            return
+        }
+        items, err := h.svc.ListEvolutionRules(from)
+        if err != nil {
+            if errors.Is(err, db.ErrTyrantNotFound) {
+                http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+                return
+            }
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(items)
+        return
+    }
 
+    switch r.Method {
+    case http.MethodGet:
+        item, err := h.svc.GetEvolutionRule(from, to)
+        if err != nil {
+            if errors.Is(err, db.ErrEvolutionNotFound) {
+                http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+                return
+            }
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(item)
+        return
+
+    case http.MethodPut:
+        if !auth.RequireAdmin(w, r, h.svc) {
+            return
+        }
+        var req evolutionRuleRequest
+        dec := json.NewDecoder(r.Body)
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(&req); err != nil {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        if req.MinLevel < 0 || req.MinXP < 0 || req.Item != nil && *req.Item == "" || from == to {
+            http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+            return
+        }
+        rule := models.EvolutionRule{From: from, To: to, MinLevel: req.MinLevel, MinXP: req.MinXP, Item: req.Item}
+        if err := h.svc.SetEvolutionRule(rule); err != nil {
+            if errors.Is(err, db.ErrTyrantNotFound) {
+                // unknown source or target tyrant
+                http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+                return
+            }
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(rule)
+        return
+
+    case http.MethodDelete:
+        if !auth.RequireAdmin(w, r, h.svc) {
+            return
+        }
+        if err := h.svc.DeleteEvolutionRule(from, to); err != nil {
+            if errors.Is(err, db.ErrEvolutionNotFound) {
+                http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+                return
+            }
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+        return
+
+    default:
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
+}
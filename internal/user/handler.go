@@ -16,16 +16,24 @@ type Service interface {
     GetUser(id string) (models.User, error)
     GetUserDetails(id string) (models.UserDetails, error)
     UpdateUser(id string, upd models.UserUpdate) (models.UserDetails, error)
+    EvolveUser(userID, to string) (models.EvolutionRule, error)
+}
+
+// EvolutionNotifier is told when a user's Tyrant evolves (e.g. so scenes can
+// show it to the table).
+type EvolutionNotifier interface {
+    NotifyEvolution(user, from, to string)
 }
 
 // Handler provides HTTP handlers for user flows.
 type Handler struct {
-    svc Service
+    svc      Service
+    notifier EvolutionNotifier
 }
 
-// NewHandler creates a new Handler.
-func NewHandler(svc Service) *Handler {
-    return &Handler{svc: svc}
+// NewHandler creates a new Handler. notifier may be nil.
+func NewHandler(svc Service, notifier EvolutionNotifier) *Handler {
+    return &Handler{svc: svc, notifier: notifier}
 }
 
 // createUserRequest represents the payload for POST /users.
@@ -35,6 +43,11 @@ type createUserRequest struct {
     Admin bool   `json:"admin"`
 }
 
+// evolveRequest represents the payload for POST /users/{id}/evolve.
+type evolveRequest struct {
+    To string `json:"to"`
+}
+
 // loginRequest represents the payload for POST /login.
 type loginRequest struct {
     ID string `json:"id"`
@@ -107,20 +120,31 @@ func (h *Handler) PostLogin(w http.ResponseWriter, r *http.Request) {
     _ = json.NewEncoder(w).Encode(user)
 }
 
-// PutUser handles PUT /users/{id}
-func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodPut {
-        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+// UsersItem handles PUT /users/{id} and POST /users/{id}/evolve
+func (h *Handler) UsersItem(w http.ResponseWriter, r *http.Request) {
+    if !strings.HasPrefix(r.URL.Path, "/users/") {
+        http.NotFound(w, r)
         return
     }
-    // Expect path /users/{id}
-    if !strings.HasPrefix(r.URL.Path, "/users/") {
+    id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+    if id == "" {
         http.NotFound(w, r)
         return
     }
-    id := strings.TrimPrefix(r.URL.Path, "/users/")
-    if id == "" || strings.Contains(id, "/") {
+    switch action {
+    case "":
+        h.putUser(w, r, id)
+    case "evolve":
+        h.postEvolve(w, r, id)
+    default:
         http.NotFound(w, r)
+    }
+}
+
+// putUser handles PUT /users/{id}
+func (h *Handler) putUser(w http.ResponseWriter, r *http.Request, id string) {
+    if r.Method != http.MethodPut {
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
         return
     }
 
@@ -145,4 +169,49 @@ func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
     _ = json.NewEncoder(w).Encode(details)
 }
 
+// postEvolve handles POST /users/{id}/evolve
+func (h *Handler) postEvolve(w http.ResponseWriter, r *http.Request, id string) {
+    if r.Method != http.MethodPost {
+        http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+        return
+    }
 
+    var req evolveRequest
+    dec := json.NewDecoder(r.Body)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&req); err != nil {
+        http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+        return
+    }
+    if req.To == "" {
+        http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+        return
+    }
+
+    rule, err := h.svc.EvolveUser(id, req.To)
+    if err != nil {
+        switch {
+        case errors.Is(err, db.ErrUserNotFound):
+            http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+        case errors.Is(err, db.ErrEvolutionNotFound), errors.Is(err, db.ErrTyrantNotFound):
+            // not an evolution of the user's current Tyrant
+            http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+        case errors.Is(err, db.ErrEvolutionRequirementsNotMet):
+            http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+        default:
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        }
+        return
+    }
+    if h.notifier != nil {
+        h.notifier.NotifyEvolution(id, rule.From, rule.To)
+    }
+
+    details, err := h.svc.GetUserDetails(id)
+    if err != nil {
+        http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(details)
+}